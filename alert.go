@@ -0,0 +1,361 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertFormat is the payload format of a webhook (see WebhookAlerter).
+type AlertFormat int
+
+const (
+	AlertSlack     AlertFormat = iota // Slack incoming webhooks (and compatible services like Mattermost): {"text": "..."}
+	AlertDiscord                      // Discord webhooks: {"content": "..."}
+	AlertPagerDuty                    // PagerDuty Events API v2 (see WebhookAlerter.RoutingKey)
+)
+
+// PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// ErrAlertQueueFull is reported when an alert is dropped because too many alerts are waiting to be sent.
+var ErrAlertQueueFull = errors.New("alert queue is full")
+
+// WebhookAlerter sends alerts to a webhook for severe logs, so that critical errors page someone.
+// It is meant to be used as a destination of a Router (with its Log method), fields must be set before the first log.
+//
+// Identical logs (same level and message) are sent once per deduplication window,
+// the next alert after the window holds the number of repeats (under DataKeyRepeatCount).
+// If no identical log comes within one more window, the repeats are sent in a summary of the first alert instead.
+// Alerts exceeding the rate limit are dropped, the next alert sent holds their count (under DataKeySuppressed).
+// Alerts are sent from a background goroutine, so logging never waits for the webhook.
+type WebhookAlerter struct {
+	Format      AlertFormat                // For ex: AlertDiscord
+	Payload     func(*Log) ([]byte, error) // For ex: a custom JSON payload (overrides Format)
+	RoutingKey  string                     // For ex: the integration key of a PagerDuty service (required by AlertPagerDuty)
+	MinLevel    LogLevel                   // For ex: LevelPanic (LevelError by default), logs without a level are ignored
+	DedupWindow time.Duration              // For ex: 1h between identical alerts (5m by default, a negative duration disables deduplication)
+	RateLimit   RateLimit                  // For ex: {PerSecond: 1.0 / 60, Burst: 5} (at most 1 alert per minute after a burst of 5 by default)
+	Client      *http.Client               // For ex: a client with a custom transport (a client with a 10s timeout by default)
+	Header      http.Header                // For ex: an "Authorization" header added to all requests
+	QueueSize   int                        // For ex: 256 alerts waiting to be sent, new alerts are dropped beyond (64 by default)
+	MaxRetries  int                        // For ex: 5 attempts after the first one (3 by default, a negative number disables retries)
+	OnError     func(error)                // For ex: write a fallback line to stderr (errors are ignored by default)
+
+	url       string
+	mu        sync.Mutex
+	seen      map[string]*alertState // By identity key (see identityKey)
+	bucket    tokenBucket
+	startOnce sync.Once
+	queue     chan alertItem
+	flushes   sync.WaitGroup // Flush calls sending to the queue, the queue is closed once they are done
+	stopped   chan struct{}
+	closed    bool
+}
+
+// alertState holds the deduplication state of identical alerts.
+type alertState struct {
+	l         *Log // First alert of the window
+	windowEnd time.Time
+	repeats   int
+}
+
+// alertItem is an alert waiting to be sent, or a flush request if done is not nil.
+type alertItem struct {
+	l    *Log
+	done chan struct{}
+}
+
+// NewWebhookAlerter returns an alerter sending alerts to the given webhook URL in the given format.
+func NewWebhookAlerter(url string, format AlertFormat) *WebhookAlerter {
+	return &WebhookAlerter{url: url, Format: format, stopped: make(chan struct{})}
+}
+
+// Log queues an alert for the log if its level is at or above the minimum level,
+// unless it is a repeat or exceeds the rate limit. It never blocks.
+func (wa *WebhookAlerter) Log(l *Log) error {
+	minLevel := wa.MinLevel
+	if minLevel == LevelUnknown {
+		minLevel = LevelError
+	}
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); !ok || lvl < minLevel {
+		return nil
+	}
+	wa.startOnce.Do(wa.start)
+
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	if wa.closed {
+		return ErrLoggerClosed
+	}
+
+	// Drop repeats
+	now := time.Now()
+	window := wa.DedupWindow
+	if window == 0 {
+		window = 5 * time.Minute
+	}
+	alert := l.clone()
+	if window > 0 {
+		key := identityKey(l)
+		if s, ok := wa.seen[key]; ok && now.Before(s.windowEnd) {
+			s.repeats++
+			return nil
+		} else if ok && s.repeats > 0 {
+			alert.Data[DataKeyRepeatCount] = s.repeats
+		}
+		for k, s := range wa.seen {
+			if now.Before(s.windowEnd) || (s.repeats > 0 && now.Before(s.windowEnd.Add(window))) {
+				continue // Repeats are kept for one more window, waiting for the next identical alert
+			}
+			if s.repeats > 0 && k != key {
+				summary := s.l.clone()
+				summary.Data[DataKeyRepeatCount] = s.repeats
+				wa.enqueue(summary, now)
+			}
+			delete(wa.seen, k)
+		}
+		if wa.seen == nil {
+			wa.seen = map[string]*alertState{}
+		}
+		wa.seen[key] = &alertState{l: alert.clone(), windowEnd: now.Add(window)}
+	}
+	wa.enqueue(alert, now)
+	return nil
+}
+
+// enqueue queues an alert, unless it exceeds the rate limit. The lock must be held.
+func (wa *WebhookAlerter) enqueue(alert *Log, now time.Time) {
+	limit := wa.RateLimit
+	if limit.PerSecond <= 0 {
+		limit = RateLimit{PerSecond: 1.0 / 60, Burst: 5}
+	}
+	if !wa.bucket.take(limit, now) {
+		wa.bucket.suppressed++
+		return
+	}
+	if wa.bucket.suppressed > 0 {
+		alert.Data[DataKeySuppressed] = wa.bucket.suppressed
+		wa.bucket.suppressed = 0
+	}
+
+	select {
+	case wa.queue <- alertItem{l: alert}:
+	default:
+		wa.report(ErrAlertQueueFull)
+	}
+}
+
+// Flush waits until the queued alerts are sent.
+func (wa *WebhookAlerter) Flush() error {
+	wa.startOnce.Do(wa.start)
+	wa.mu.Lock()
+	if wa.closed {
+		wa.mu.Unlock()
+		return nil
+	}
+	wa.flushes.Add(1)
+	wa.mu.Unlock()
+
+	// Sent without holding the lock, so that logging doesn't wait for the queue to have room
+	done := make(chan struct{})
+	wa.queue <- alertItem{done: done}
+	wa.flushes.Done()
+	<-done
+	return nil
+}
+
+// Close waits until the queued alerts are sent and stops the background goroutine,
+// logs are dropped afterwards (and ErrLoggerClosed is returned).
+func (wa *WebhookAlerter) Close() error {
+	wa.startOnce.Do(wa.start)
+	wa.mu.Lock()
+	if wa.closed {
+		wa.mu.Unlock()
+		return nil
+	}
+	wa.closed = true
+	wa.mu.Unlock()
+	wa.flushes.Wait()
+	close(wa.queue)
+	<-wa.stopped
+	return nil
+}
+
+// start creates the queue and starts the background goroutine sending alerts.
+func (wa *WebhookAlerter) start() {
+	size := wa.QueueSize
+	if size <= 0 {
+		size = 64
+	}
+	wa.queue = make(chan alertItem, size)
+
+	go func() {
+		defer close(wa.stopped)
+		for item := range wa.queue {
+			if item.done != nil {
+				close(item.done)
+				continue
+			}
+			if err := wa.send(item.l); err != nil {
+				wa.report(err)
+			}
+		}
+	}()
+}
+
+// send posts an alert, retrying with an exponential backoff when the error is temporary.
+func (wa *WebhookAlerter) send(l *Log) error {
+	payload, err := wa.payload(l)
+	if err != nil {
+		return fmt.Errorf("alert payload: %w", err)
+	}
+	retries := wa.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		temporary, err := wa.post(payload)
+		if err == nil || !temporary || attempt >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post posts a payload to the webhook, it reports whether the error (if any) is temporary.
+func (wa *WebhookAlerter) post(payload []byte) (temporary bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, wa.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	for k, values := range wa.Header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := wa.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("send alert: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		temporary = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return temporary, fmt.Errorf("send alert: unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
+
+// report reports an error to the error handler (if any).
+func (wa *WebhookAlerter) report(err error) {
+	if wa.OnError != nil {
+		wa.OnError(err)
+	}
+}
+
+// Maximum length of alert texts (Discord messages are limited to 2000 characters).
+const maxAlertTextLen = 2000
+
+// payload returns the payload of an alert.
+func (wa *WebhookAlerter) payload(l *Log) ([]byte, error) {
+	if wa.Payload != nil {
+		return wa.Payload(l)
+	}
+	switch wa.Format {
+	case AlertDiscord:
+		return json.Marshal(map[string]any{"content": alertText(l)})
+	case AlertPagerDuty:
+		return json.Marshal(pagerDutyEvent(l, wa.RoutingKey))
+	}
+	return json.Marshal(map[string]any{"text": alertText(l)})
+}
+
+// alertText returns the text of an alert: the level and message, then the data (one value per line).
+func alertText(l *Log) string {
+	var sb strings.Builder
+	if lvl, ok := l.Data[DataKeyLevel]; ok {
+		fmt.Fprintf(&sb, "[%v] ", lvl)
+	}
+	sb.WriteString(l.Message)
+	for _, k := range sortedDataKeys(l) {
+		if k == DataKeyLevel {
+			continue
+		}
+		v := l.Data[k]
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(time.RFC3339)
+		}
+		fmt.Fprintf(&sb, "\n%s: %v", k, v)
+	}
+	text := sb.String()
+	if len(text) > maxAlertTextLen {
+		text = strings.ToValidUTF8(text[:maxAlertTextLen-3], "") + "..."
+	}
+	return text
+}
+
+// Maps log levels to PagerDuty severities.
+var pagerDutySeverities = map[LogLevel]string{
+	LevelUnknown: "info",
+	LevelTrace:   "info",
+	LevelDebug:   "info",
+	LevelInfo:    "info",
+	LevelWarn:    "warning",
+	LevelError:   "error",
+	LevelPanic:   "critical",
+	LevelFatal:   "critical",
+}
+
+// pagerDutyEvent returns the PagerDuty event of an alert,
+// identical logs share a deduplication key so that they are grouped in the same incident.
+func pagerDutyEvent(l *Log, routingKey string) map[string]any {
+	summary := l.Message
+	if len(summary) > 1024 {
+		summary = strings.ToValidUTF8(summary[:1021], "") + "..."
+	}
+	dedupKey := identityKey(l)
+	if len(dedupKey) > 255 {
+		dedupKey = dedupKey[:255]
+	}
+	payload := map[string]any{"summary": summary, "source": gelfHost, "severity": "error"}
+	details := map[string]any{}
+	for k, v := range l.Data {
+		switch k {
+		case DataKeyLevel:
+			if lvl, ok := levelOf(v); ok {
+				payload["severity"] = pagerDutySeverities[baseLevel(lvl)]
+				continue
+			}
+		case DataKeyTimestamp:
+			if t, ok := v.(time.Time); ok {
+				payload["timestamp"] = t.Format(time.RFC3339Nano)
+				continue
+			}
+		case DataKeyComponent:
+			payload["component"] = v
+		}
+		details[k] = stringify(v, false)
+	}
+	if len(details) > 0 {
+		payload["custom_details"] = details
+	}
+	return map[string]any{
+		"routing_key":  routingKey,
+		"event_action": "trigger",
+		"dedup_key":    dedupKey,
+		"payload":      payload,
+	}
+}
@@ -0,0 +1,118 @@
+package logs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookAlerterLogDoesNotWaitForFlush(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer srv.Close()
+
+	wa := NewWebhookAlerter(srv.URL, AlertSlack)
+	wa.QueueSize = 1
+	wa.RateLimit = RateLimit{PerSecond: 1000, Burst: 1000}
+	wa.OnError = func(error) {}
+	wa.Log(NewLog("first", WithLevel(LevelError.String())))  // Being sent (blocked by the server)
+	time.Sleep(50 * time.Millisecond)                        // Let the goroutine take the first alert
+	wa.Log(NewLog("second", WithLevel(LevelError.String()))) // Fills the queue
+
+	flushed := make(chan struct{})
+	go func() { wa.Flush(); close(flushed) }() // Waits for room in the queue
+	time.Sleep(50 * time.Millisecond)
+
+	logged := make(chan struct{})
+	go func() { wa.Log(NewLog("third", WithLevel(LevelError.String()))); close(logged) }()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("Log blocked while Flush was waiting for the queue")
+	}
+
+	close(release)
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush didn't return")
+	}
+	wa.Close()
+}
+
+func TestWebhookAlerterKeepsRepeatsOfOtherAlerts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	repeats := map[string]any{}
+	wa := NewWebhookAlerter(srv.URL, AlertSlack)
+	wa.DedupWindow = 50 * time.Millisecond
+	wa.RateLimit = RateLimit{PerSecond: 1000, Burst: 1000}
+	wa.Payload = func(l *Log) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		repeats[l.Message] = l.Data[DataKeyRepeatCount]
+		return []byte("{}"), nil
+	}
+	wa.Log(NewLog("a", WithLevel(LevelError.String())))
+	wa.Log(NewLog("a", WithLevel(LevelError.String()))) // Repeat
+	time.Sleep(60 * time.Millisecond)
+	wa.Log(NewLog("b", WithLevel(LevelError.String()))) // Cleans up expired entries
+	wa.Log(NewLog("a", WithLevel(LevelError.String())))
+	if err := wa.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if repeats["a"] != 1 {
+		t.Fatalf("repeat count = %v, want 1", repeats["a"])
+	}
+}
+
+func TestWebhookAlerterSummarizesExpiredRepeats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var sent []*Log
+	wa := NewWebhookAlerter(srv.URL, AlertSlack)
+	wa.DedupWindow = 30 * time.Millisecond
+	wa.RateLimit = RateLimit{PerSecond: 1000, Burst: 1000}
+	wa.Payload = func(l *Log) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, l)
+		return []byte("{}"), nil
+	}
+	wa.Log(NewLog("a", WithLevel(LevelError.String())))
+	wa.Log(NewLog("a", WithLevel(LevelError.String()))) // Repeat
+	wa.Log(NewLog("a", WithLevel(LevelError.String()))) // Repeat
+	time.Sleep(70 * time.Millisecond)                   // More than two windows
+	wa.Log(NewLog("b", WithLevel(LevelError.String()))) // Sends the summary of "a"
+	wa.mu.Lock()
+	n := len(wa.seen)
+	wa.mu.Unlock()
+	if err := wa.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 {
+		t.Fatalf("%d deduplication entries, want 1", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 3 {
+		t.Fatalf("%d alerts sent, want 3", len(sent))
+	}
+	if sent[1].Message != "a" || sent[1].Data[DataKeyRepeatCount] != 2 {
+		t.Fatalf("summary = %q with %v repeats, want %q with 2 repeats", sent[1].Message, sent[1].Data[DataKeyRepeatCount], "a")
+	}
+	if _, ok := sent[2].Data[DataKeyRepeatCount]; sent[2].Message != "b" || ok {
+		t.Fatalf("last alert = %q with %v repeats, want %q without repeats", sent[2].Message, sent[2].Data[DataKeyRepeatCount], "b")
+	}
+}
@@ -0,0 +1,130 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// DataKeyPrevHash holds the hash of the previous record in audit logs (see AuditChain).
+const DataKeyPrevHash = dataKeyPrefix + "prev_hash"
+
+// ErrAuditChainBroken is returned by VerifyAuditChain when a record doesn't hold the hash of the previous one,
+// which means that records were deleted, inserted or modified.
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// AuditChain makes logs tamper-evident: each record holds the hash of the previous record (under DataKeyPrevHash),
+// so deleting or modifying a record breaks the chain (see VerifyAuditChain).
+//
+// Records are hashed with SHA-256, or signed with HMAC-SHA256 when a key is given:
+// without the key, records cannot be modified even if the chain is recomputed.
+// The last record is only protected once another record has been written after it.
+//
+// Its serializer must be used for a single output, with a single-line JSON serializer
+// (for ex: AsJSON or AsOrderedJSON) and without log prefix or suffix, so that each line of the output is a record.
+//
+// The chain advances when a record is serialized. To keep it valid when a write fails, add the chain to the hooks
+// of the logger: it then moves back to the previous record when the last serialized record fails to be written.
+// In async mode, the records serialized after a failed one (and not written yet) still refer to it.
+type AuditChain struct {
+	mu     sync.Mutex
+	key    []byte
+	prev   string // Hash of the last record, hex-encoded
+	before string // Hash of the record preceding the last one, restored if the last record fails to be written
+}
+
+// AuditChain can be used as a hook to handle failed writes.
+var _ Hook = (*AuditChain)(nil)
+
+// NewAuditChain returns a new chain, key is optional (see AuditChain).
+// To continue the chain of an existing output (for ex: after a restart), use ContinueAuditChain.
+func NewAuditChain(key []byte) *AuditChain { return &AuditChain{key: key} }
+
+// ContinueAuditChain returns a chain following the record with the given hash (see VerifyAuditChain).
+func ContinueAuditChain(key []byte, prev string) *AuditChain {
+	return &AuditChain{key: key, prev: prev}
+}
+
+// Serializer returns a serializer adding the hash of the previous record to logs before serializing them with s.
+func (ac *AuditChain) Serializer(s Serializer) Serializer {
+	return func(l *Log) []byte {
+		ac.mu.Lock()
+		defer ac.mu.Unlock()
+
+		chained := l.clone()
+		chained.Data[DataKeyPrevHash] = ac.prev
+		b := s(chained)
+		ac.before, ac.prev = ac.prev, hashRecord(ac.key, b)
+		return b
+	}
+}
+
+// BeforeSerialize does nothing, it allows AuditChain to implement Hook.
+func (ac *AuditChain) BeforeSerialize(l *Log) {}
+
+// AfterWrite moves the chain back to the previous record if the last serialized record failed to be written,
+// so that the next record follows the last record of the output.
+func (ac *AuditChain) AfterWrite(l *Log, b []byte, err error) {
+	if err == nil || b == nil {
+		return
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if hashRecord(ac.key, bytes.TrimSuffix(b, []byte("\n"))) == ac.prev {
+		ac.prev = ac.before
+	}
+}
+
+// hashRecord returns the hex-encoded hash (or HMAC if a key is given) of a record.
+func hashRecord(key, record []byte) string {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAuditChain reads records written with an AuditChain serializer (one per line)
+// and checks that each of them holds the hash of the previous one.
+// Prev is the hash of the record preceding the first one (empty if the first record starts the chain,
+// or the hash returned when verifying the previous file for ex: with file rotation).
+//
+// It returns the hash of the last record (to continue the chain with ContinueAuditChain)
+// and ErrAuditChainBroken (wrapped, with the line number) at the first record that doesn't match.
+func VerifyAuditChain(r io.Reader, key []byte, prev string) (last string, err error) {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		record, err := br.ReadBytes('\n')
+		if err == io.EOF && len(record) == 0 {
+			return prev, nil
+		} else if err != nil && err != io.EOF {
+			return prev, err
+		}
+		record = bytes.TrimSuffix(record, []byte("\n"))
+		if len(record) == 0 {
+			continue
+		}
+
+		l, decodeErr := decodeJSONLog(record)
+		if decodeErr != nil {
+			return prev, fmt.Errorf("%w at line %d: %v", ErrAuditChainBroken, line, decodeErr)
+		}
+		if got, _ := l.Data[DataKeyPrevHash].(string); got != prev {
+			return prev, fmt.Errorf("%w at line %d: previous hash %q, expected %q", ErrAuditChainBroken, line, got, prev)
+		}
+		prev = hashRecord(key, record)
+		if err == io.EOF {
+			return prev, nil
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails the writes whose index (starting at 0) is in failures.
+type flakyWriter struct {
+	buf      bytes.Buffer
+	writes   int
+	failures map[int]bool
+}
+
+func (fw *flakyWriter) Write(b []byte) (int, error) {
+	defer func() { fw.writes++ }()
+	if fw.failures[fw.writes] {
+		return 0, errors.New("write failed")
+	}
+	return fw.buf.Write(b)
+}
+
+func TestAuditChainSurvivesFailedWrites(t *testing.T) {
+	fw := &flakyWriter{failures: map[int]bool{1: true}}
+	ac := NewAuditChain([]byte("secret"))
+	dl := &DefaultLogger{Writers: []io.Writer{fw}, Serializer: ac.Serializer(AsJSON), Hooks: []Hook{ac}}
+	for _, msg := range []string{"first", "lost", "third"} {
+		dl.Info(msg)
+	}
+	if strings.Count(fw.buf.String(), "\n") != 2 {
+		t.Fatalf("unexpected output: %q", fw.buf.String())
+	}
+	if _, err := VerifyAuditChain(&fw.buf, []byte("secret"), ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditChainKeepsTimeFormat(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	format := &TimeFormat{Layout: TimeUnixMilli}
+	var audited, plain bytes.Buffer
+	for _, out := range []struct {
+		w io.Writer
+		s Serializer
+	}{{&audited, NewAuditChain(nil).Serializer(AsJSON)}, {&plain, AsJSON}} {
+		dl := &DefaultLogger{Writers: []io.Writer{out.w}, Serializer: out.s, TimeFormat: format}
+		dl.Log(NewLog("msg", WithData(DataKeyTimestamp, ts)))
+	}
+	want := `"` + DataKeyTimestamp + `":1714566600000`
+	if !strings.Contains(audited.String(), want) || !strings.Contains(plain.String(), want) {
+		t.Fatalf("audited log %q, plain log %q, want %s in both", audited.String(), plain.String(), want)
+	}
+}
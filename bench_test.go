@@ -0,0 +1,60 @@
+package logs
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// Benchmarks an Info log with 5 fields and a timestamp, written as JSON to io.Discard.
+
+func newBenchmarkLogger() *DefaultLogger {
+	return &DefaultLogger{Writers: []io.Writer{io.Discard}, Serializer: AsJSON, BaseOptions: []LogOption{WithTimestamp()}}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	dl := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dl.Info("request handled",
+			WithString("method", "GET"),
+			WithString("path", "/users"),
+			WithInt64("status", 200),
+			WithDuration("latency", 1500*time.Microsecond),
+			WithBool("cached", true),
+		)
+	}
+}
+
+func BenchmarkLogFields(b *testing.B) {
+	dl := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dl.LogFields(LevelInfo, "request handled",
+			String("method", "GET"),
+			String("path", "/users"),
+			Int("status", 200),
+			Duration("latency", 1500*time.Microsecond),
+			Bool("cached", true),
+		)
+	}
+}
+
+func BenchmarkLogFieldsParallel(b *testing.B) {
+	dl := newBenchmarkLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			dl.LogFields(LevelInfo, "request handled",
+				String("method", "GET"),
+				String("path", "/users"),
+				Int("status", 200),
+				Duration("latency", 1500*time.Microsecond),
+				Bool("cached", true),
+			)
+		}
+	})
+}
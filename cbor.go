@@ -0,0 +1,377 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Returns the CBOR (RFC 8949) representation of a log, a binary format that is more compact
+// and cheaper to encode than JSON. It holds a map with the "message" and "data" keys (like AsJSON),
+// map keys are sorted and timestamps are encoded as RFC 3339 date/time strings (tag 0).
+// Values that have no direct CBOR equivalent (for ex: structs) are encoded like their JSON representation.
+// Logs can be read back with NewCBORReader (without prefix or suffix, except for line breaks).
+// This function will panic if the log cannot be encoded.
+func AsCBOR(l *Log) []byte {
+	b, err := encodeCBORLog(l)
+	if err != nil {
+		b, err = encodeCBORLog(withoutCycles(l))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// encodeCBORLog returns the CBOR representation of a log.
+func encodeCBORLog(l *Log) ([]byte, error) {
+	data := make(map[string]any, len(l.Data))
+	for k, v := range l.Data {
+		data[k] = stringify(v, false)
+	}
+	buf := &bytes.Buffer{}
+	err := encodeCBOR(buf, map[string]any{"message": l.Message, "data": data}, 0)
+	return buf.Bytes(), err
+}
+
+// Maximum nesting depth of encoded CBOR values, deeper values are assumed to be self-referential.
+const maxCBORDepth = 1000
+
+// errCBORTooDeep is returned when a value is too deeply nested to be encoded.
+var errCBORTooDeep = errors.New("cbor: value too deeply nested")
+
+// Maximum length of the byte and text strings decoded by a CBOR reader.
+const maxCBORStringSize = 64 << 20
+
+// errCBORTooLarge is returned when a decoded string is longer than maxCBORStringSize.
+var errCBORTooLarge = errors.New("cbor: string too large")
+
+// Number of array elements or map entries allocated at most before they are decoded:
+// lengths come from the input, so larger arrays and maps grow as their items are read.
+const maxCBORPrealloc = 1024
+
+// preallocated returns the capacity to allocate for an array or map of the given length.
+func preallocated(n uint64) int {
+	if n > maxCBORPrealloc {
+		return maxCBORPrealloc
+	}
+	return int(n)
+}
+
+// encodeCBOR writes the CBOR representation of a value to buf.
+func encodeCBOR(buf *bytes.Buffer, v any, depth int) error {
+	if depth > maxCBORDepth {
+		return errCBORTooDeep
+	}
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case string:
+		writeCBORHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		writeCBORHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case int:
+		writeCBORInt(buf, int64(v))
+	case int8:
+		writeCBORInt(buf, int64(v))
+	case int16:
+		writeCBORInt(buf, int64(v))
+	case int32:
+		writeCBORInt(buf, int64(v))
+	case int64:
+		writeCBORInt(buf, v)
+	case time.Duration:
+		writeCBORInt(buf, int64(v))
+	case uint:
+		writeCBORHead(buf, 0, uint64(v))
+	case uint8:
+		writeCBORHead(buf, 0, uint64(v))
+	case uint16:
+		writeCBORHead(buf, 0, uint64(v))
+	case uint32:
+		writeCBORHead(buf, 0, uint64(v))
+	case uint64:
+		writeCBORHead(buf, 0, v)
+	case float32:
+		writeCBORFloat(buf, float64(v))
+	case float64:
+		writeCBORFloat(buf, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeCBORInt(buf, i)
+		} else if f, err := v.Float64(); err == nil {
+			writeCBORFloat(buf, f)
+		} else {
+			return err
+		}
+	case time.Time:
+		writeCBORHead(buf, 6, 0)
+		return encodeCBOR(buf, v.Format(time.RFC3339Nano), depth+1)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeCBORHead(buf, 5, uint64(len(v)))
+		for _, k := range keys {
+			encodeCBOR(buf, k, depth+1)
+			if err := encodeCBOR(buf, v[k], depth+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		writeCBORHead(buf, 4, uint64(len(v)))
+		for _, elem := range v {
+			if err := encodeCBOR(buf, elem, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		// Encode other values like their JSON representation
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			buf.WriteByte(0xf6)
+			return nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var generic any
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+		return encodeCBOR(buf, generic, depth+1)
+	}
+	return nil
+}
+
+// writeCBORHead writes the head of a CBOR data item with the given major type and argument.
+func writeCBORHead(buf *bytes.Buffer, major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, arg)
+	}
+}
+
+// writeCBORInt writes a signed integer.
+func writeCBORInt(buf *bytes.Buffer, i int64) {
+	if i < 0 {
+		writeCBORHead(buf, 1, uint64(-(i + 1)))
+		return
+	}
+	writeCBORHead(buf, 0, uint64(i))
+}
+
+// writeCBORFloat writes a double precision float.
+func writeCBORFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(7<<5 | 27)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+// NewCBORReader returns a reader reading logs written as CBOR (for ex: by AsCBOR) from r.
+// Top-level items that are not maps (for ex: the line breaks added by loggers) are skipped.
+func NewCBORReader(r io.Reader) *Reader { return &Reader{r: bufio.NewReader(r), cbor: true} }
+
+// nextCBORLog decodes the next top-level CBOR map as a log.
+func (lr *Reader) nextCBORLog() (*Log, error) {
+	for {
+		c, err := lr.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if c[0]>>5 != 5 {
+			// Skip top-level item that is not a map
+			if _, err := lr.decodeCBOR(0); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			continue
+		}
+
+		v, err := lr.decodeCBOR(0)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		m, _ := v.(map[string]any)
+		l := &Log{Data: map[string]any{}}
+		l.Message, _ = m["message"].(string)
+		if data, ok := m["data"].(map[string]any); ok {
+			l.Data = data
+		}
+		return l, nil
+	}
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, for items that have started to be read.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeCBOR decodes the next CBOR data item.
+// Integers are decoded as int64 (or uint64 if they overflow), floats as float64,
+// maps with text keys as map[string]any, arrays as []any and tag 0 date/time strings as time.Time.
+func (lr *Reader) decodeCBOR(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, errCBORTooDeep
+	}
+	major, arg, err := lr.readCBORHead()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -int64(arg) - 1, nil
+	case 2, 3:
+		if arg > maxCBORStringSize {
+			return nil, errCBORTooLarge
+		}
+		// Read in chunks, so that the memory used is bounded by the size of the input (not by the announced length)
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, lr.r, int64(arg)); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if major == 2 {
+			return buf.Bytes(), nil
+		}
+		return buf.String(), nil
+	case 4:
+		arr := make([]any, 0, preallocated(arg))
+		for i := uint64(0); i < arg; i++ {
+			elem, err := lr.decodeCBOR(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+		return arr, nil
+	case 5:
+		m := make(map[string]any, preallocated(arg))
+		for i := uint64(0); i < arg; i++ {
+			k, err := lr.decodeCBOR(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			v, err := lr.decodeCBOR(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = v
+		}
+		return m, nil
+	case 6:
+		v, err := lr.decodeCBOR(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok && arg == 0 {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, nil
+			}
+		}
+		return v, nil
+	}
+
+	// Major type 7: simple values and floats
+	switch arg {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	}
+	return math.Float64frombits(arg), nil
+}
+
+// readCBORHead reads the head of a CBOR data item.
+// Floats are returned as the bits of their float64 conversion.
+func (lr *Reader) readCBORHead() (major byte, arg uint64, err error) {
+	c, err := lr.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := c>>5, c&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d (indefinite lengths are not supported)", info)
+	}
+	b := make([]byte, 1<<(info-24))
+	if _, err := io.ReadFull(lr.r, b); err != nil {
+		return 0, 0, unexpectedEOF(err)
+	}
+	for _, c := range b {
+		arg = arg<<8 | uint64(c)
+	}
+	if major == 7 {
+		switch info {
+		case 25:
+			arg = math.Float64bits(float64(halfToFloat32(uint16(arg))))
+		case 26:
+			arg = math.Float64bits(float64(math.Float32frombits(uint32(arg))))
+		}
+	}
+	return major, arg, nil
+}
+
+// halfToFloat32 converts a half precision float to a float32.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13) // Infinity or NaN
+	case exp == 0 && frac == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		v := float32(frac) / (1 << 24)
+		if sign != 0 {
+			return -v
+		}
+		return v
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
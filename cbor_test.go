@@ -0,0 +1,78 @@
+package logs
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		data map[string]any
+		want map[string]any
+	}{
+		{"empty", map[string]any{}, map[string]any{}},
+		{"string", map[string]any{"k": "v"}, map[string]any{"k": "v"}},
+		{"integers", map[string]any{"pos": 42, "neg": -7}, map[string]any{"pos": int64(42), "neg": int64(-7)}},
+		{"float", map[string]any{"f": 1.5}, map[string]any{"f": 1.5}},
+		{"bool and nil", map[string]any{"t": true, "f": false, "n": nil}, map[string]any{"t": true, "f": false, "n": nil}},
+		{"time", map[string]any{"ts": ts}, map[string]any{"ts": ts}},
+		{"nested", map[string]any{"m": map[string]any{"a": []any{"x", 1}}}, map[string]any{"m": map[string]any{"a": []any{"x", int64(1)}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := AsCBOR(&Log{Message: "msg", Data: tt.data})
+			l, err := NewCBORReader(bytes.NewReader(b)).Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Message != "msg" {
+				t.Errorf("message = %q", l.Message)
+			}
+			if !reflect.DeepEqual(l.Data, tt.want) {
+				t.Errorf("data = %#v, want %#v", l.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestCBORReaderSkipsLineBreaks(t *testing.T) {
+	var buf bytes.Buffer
+	for _, msg := range []string{"a", "b"} {
+		buf.Write(AsCBOR(NewLog(msg)))
+		buf.WriteByte('\n')
+	}
+	logs, err := NewCBORReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Message != "a" || logs[1].Message != "b" {
+		t.Fatalf("got %v", logs)
+	}
+}
+
+func TestCBORReaderRejectsHugeLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		// Map with one entry whose key is a byte/text string, an array or a map announcing 2^64-1 items
+		{"byte string", []byte{0xa1, 0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"text string", []byte{0xa1, 0x7b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"text string under the limit", []byte{0xa1, 0x7a, 0x01, 0x00, 0x00, 0x00}},
+		{"array", []byte{0xa1, 0x61, 'k', 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"map", []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCBORReader(bytes.NewReader(tt.input)).Next()
+			if err == nil || err == io.EOF {
+				t.Fatalf("err = %v, want a decoding error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package logs
+
+import "time"
+
+// Clock provides the current time, for ex: a fake clock in tests so that timestamps are deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+// now returns the current time according to the clock of the log (the system clock by default).
+func (l *Log) now() time.Time {
+	if l.clock != nil {
+		return l.clock.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,314 @@
+// Command logs reads files produced by github.com/ejuju/go-logs,
+// filters their logs and prints them in a human-readable format.
+//
+// Usage:
+//
+//	logs [-f] [-level LEVEL] [-where KEY=VALUE]... [-format auto|console|json|text] [FILE]...
+//	logs -verify [-key-file FILE] [FILE]...
+//	logs query [--since DURATION|TIME] [--until DURATION|TIME] [--level LEVEL] [--where EXPR]... [--format FORMAT] [FILE]...
+//
+// Logs are read from the standard input when no file is given.
+// The query subcommand selects logs by creation time and with filter expressions,
+// for ex: logs query --since 1h --level ERROR --where 'data.user_id == "42"' app.log
+// (see parseExpr for the syntax of expressions).
+// With -verify, the files are checked to form a single audit chain, in the given order (see logs.AuditChain).
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ejuju/go-logs"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "query" {
+		p, paths := parseQuery(os.Args[2:], os.Stdout)
+		p.printFiles(paths, false)
+		return
+	}
+
+	cmd := parseCommand(os.Args[1:], os.Stdout)
+	if cmd.verify {
+		verifyFiles(cmd.paths, cmd.keyFile)
+		return
+	}
+	cmd.printer.printFiles(cmd.paths, cmd.follow)
+}
+
+// command holds the flags and arguments of the main command.
+type command struct {
+	printer *printer
+	paths   []string
+	follow  bool
+	verify  bool
+	keyFile string
+}
+
+// parseCommand parses the flags of the main command, matching logs are printed to out.
+func parseCommand(args []string, out io.Writer) *command {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := fs.Bool("f", false, "keep reading files as they grow (like tail -f)")
+	minLevel := fs.String("level", "", "only print logs at or above this level (for ex: WARN)")
+	format := fs.String("format", "auto", "output format: auto (console on a terminal, JSON otherwise), console, json or text")
+	var wheres whereFlags
+	fs.Var(&wheres, "where", "only print logs where the data KEY (or \"message\") equals VALUE, can be repeated")
+	verify := fs.Bool("verify", false, "verify the audit chain of the files instead of printing them")
+	keyFile := fs.String("key-file", "", "file holding the HMAC key of the audit chain (for -verify)")
+	fs.Parse(args)
+
+	f := &filter{wheres: wheres}
+	if *minLevel != "" {
+		lvl, err := logs.ParseLevel(*minLevel)
+		if err != nil {
+			exitf("%s", err)
+		}
+		f.minLevel = lvl
+	}
+
+	return &command{
+		printer: &printer{out: out, serializer: serializerFor(*format, out), filter: f},
+		paths:   fs.Args(),
+		follow:  *follow,
+		verify:  *verify,
+		keyFile: *keyFile,
+	}
+}
+
+// parseQuery parses the flags of the query subcommand,
+// it returns the printer of the matching logs (printed to out) and the files to read.
+func parseQuery(args []string, out io.Writer) (*printer, []string) {
+	fs := flag.NewFlagSet("logs query", flag.ExitOnError)
+	since := fs.String("since", "", "only print logs created during this duration until now (for ex: 1h) or since this time (RFC 3339)")
+	until := fs.String("until", "", "only print logs created before this duration ago (for ex: 10m) or before this time (RFC 3339)")
+	minLevel := fs.String("level", "", "only print logs at or above this level (for ex: ERROR)")
+	format := fs.String("format", "auto", "output format: auto (console on a terminal, JSON otherwise), console, json or text")
+	var wheres []string
+	fs.Func("where", "only print logs matching this expression (for ex: 'data.user_id == \"42\"'), can be repeated", func(s string) error {
+		wheres = append(wheres, s)
+		return nil
+	})
+	fs.Parse(args)
+
+	f := &filter{}
+	var err error
+	if *minLevel != "" {
+		if f.minLevel, err = logs.ParseLevel(*minLevel); err != nil {
+			exitf("%s", err)
+		}
+	}
+	if f.since, err = parseTimeFlag(*since); err != nil {
+		exitf("--since: %s", err)
+	}
+	if f.until, err = parseTimeFlag(*until); err != nil {
+		exitf("--until: %s", err)
+	}
+	for _, where := range wheres {
+		e, err := parseExpr(where)
+		if err != nil {
+			exitf("--where %q: %s", where, err)
+		}
+		f.exprs = append(f.exprs, e)
+	}
+
+	return &printer{out: out, serializer: serializerFor(*format, out), filter: f}, fs.Args()
+}
+
+// parseTimeFlag parses a time given as a duration before now (for ex: "1h") or in the RFC 3339 format.
+// The zero time is returned for an empty string.
+func parseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// serializerFor returns the serializer for the given output format, printed to out.
+func serializerFor(format string, out io.Writer) logs.Serializer {
+	serializer, ok := map[string]logs.Serializer{
+		"auto":    logs.AsConsoleFor(out),
+		"console": logs.AsConsole,
+		"json":    logs.AsJSON,
+		"text":    logs.AsPlainText,
+	}[format]
+	if !ok {
+		exitf("unknown format %q", format)
+	}
+	return serializer
+}
+
+// printFiles prints the matching logs of the files, or of the standard input if there are none.
+func (p *printer) printFiles(paths []string, follow bool) {
+	// Read standard input
+	if len(paths) == 0 {
+		if err := p.print(os.Stdin); err != nil {
+			exitf("read standard input: %s", err)
+		}
+		return
+	}
+
+	// Read files concurrently (so that all of them can be followed)
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			exitf("%s", err)
+		}
+		var r io.Reader = file
+		if follow {
+			r = &followReader{f: file}
+		}
+
+		wg.Add(1)
+		go func(path string, r io.Reader) {
+			defer wg.Done()
+			if err := p.print(r); err != nil {
+				exitf("read %s: %s", path, err)
+			}
+		}(path, r)
+	}
+	wg.Wait()
+}
+
+// verifyFiles checks that the files (or the standard input) form a single audit chain.
+func verifyFiles(paths []string, keyFile string) {
+	var key []byte
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			exitf("%s", err)
+		}
+		key = bytes.TrimSpace(b)
+	}
+
+	if len(paths) == 0 {
+		if _, err := logs.VerifyAuditChain(os.Stdin, key, ""); err != nil {
+			exitf("verify standard input: %s", err)
+		}
+		fmt.Println("audit chain ok")
+		return
+	}
+	prev := ""
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			exitf("%s", err)
+		}
+		prev, err = logs.VerifyAuditChain(file, key, prev)
+		file.Close()
+		if err != nil {
+			exitf("verify %s: %s", path, err)
+		}
+	}
+	fmt.Printf("audit chain ok (%d files)\n", len(paths))
+}
+
+// printer prints the logs matching a filter.
+type printer struct {
+	mu         sync.Mutex
+	out        io.Writer
+	serializer logs.Serializer
+	filter     *filter
+}
+
+// print prints the matching logs read from r.
+func (p *printer) print(r io.Reader) error {
+	lr := logs.NewReader(r)
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !p.filter.match(l) {
+			continue
+		}
+		p.mu.Lock()
+		p.out.Write(append(p.serializer(l), '\n'))
+		p.mu.Unlock()
+	}
+}
+
+// filter selects logs by level, creation time and data values.
+type filter struct {
+	minLevel     logs.LogLevel
+	wheres       whereFlags
+	since, until time.Time // Ignored when zero
+	exprs        []expr
+}
+
+// match reports whether a log should be printed.
+func (f *filter) match(l *logs.Log) bool {
+	if f.minLevel != logs.LevelUnknown {
+		label, _ := l.Data[logs.DataKeyLevel].(string)
+		if lvl, err := logs.ParseLevel(label); err != nil || lvl < f.minLevel {
+			return false
+		}
+	}
+	if !f.since.IsZero() || !f.until.IsZero() {
+		t, ok := l.Data[logs.DataKeyTimestamp].(time.Time)
+		if !ok || (!f.since.IsZero() && t.Before(f.since)) || (!f.until.IsZero() && !t.Before(f.until)) {
+			return false
+		}
+	}
+	for _, e := range f.exprs {
+		if !e.eval(l) {
+			return false
+		}
+	}
+	for _, w := range f.wheres {
+		v := any(l.Message)
+		if w.key != "message" {
+			v = l.Data[w.key]
+		}
+		if fmt.Sprint(v) != w.value {
+			return false
+		}
+	}
+	return true
+}
+
+// whereFlags holds the values of the -where flags.
+type whereFlags []struct{ key, value string }
+
+func (wf *whereFlags) String() string { return fmt.Sprint(*wf) }
+
+func (wf *whereFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected KEY=VALUE, got %q", s)
+	}
+	*wf = append(*wf, struct{ key, value string }{key, value})
+	return nil
+}
+
+// followReader reads a file, waiting for more data at the end of the file instead of returning io.EOF.
+type followReader struct {
+	f *os.File
+}
+
+func (fr *followReader) Read(b []byte) (int, error) {
+	for {
+		n, err := fr.f.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// exitf prints an error message and exits.
+func exitf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "logs: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ejuju/go-logs"
+)
+
+// sampleLogs returns sample logs and their serialization as JSON lines.
+func sampleLogs() ([]*logs.Log, string) {
+	start := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	entries := []*logs.Log{
+		logs.NewLog("server started", logs.WithLevel(logs.LevelInfo.String()),
+			logs.WithData(logs.DataKeyTimestamp, start), logs.WithData("port", 8080)),
+		logs.NewLog("slow request", logs.WithLevel(logs.LevelWarn.String()),
+			logs.WithData(logs.DataKeyTimestamp, start.Add(time.Hour)), logs.WithData("user", "42")),
+		logs.NewLog("request failed", logs.WithLevel(logs.LevelError.String()),
+			logs.WithData(logs.DataKeyTimestamp, start.Add(2*time.Hour)), logs.WithData("user", 7)),
+	}
+	var sb strings.Builder
+	for _, l := range entries {
+		sb.Write(logs.AsJSON(l))
+		sb.WriteByte('\n')
+	}
+	return entries, sb.String()
+}
+
+// messages returns the messages of the JSON logs printed to out.
+func messages(t *testing.T, out string) []string {
+	t.Helper()
+	msgs := []string{}
+	lr := logs.NewReader(strings.NewReader(out))
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return msgs
+		} else if err != nil {
+			t.Fatalf("read output %q: %s", out, err)
+		}
+		msgs = append(msgs, l.Message)
+	}
+}
+
+func TestCommandFilters(t *testing.T) {
+	_, input := sampleLogs()
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: nil, want: []string{"server started", "slow request", "request failed"}},
+		{args: []string{"-level", "WARN"}, want: []string{"slow request", "request failed"}},
+		{args: []string{"-level", "error"}, want: []string{"request failed"}},
+		{args: []string{"-where", "user=42"}, want: []string{"slow request"}},
+		{args: []string{"-where", "user=7"}, want: []string{"request failed"}},
+		{args: []string{"-where", "message=server started"}, want: []string{"server started"}},
+		{args: []string{"-where", "user=42", "-where", "message=request failed"}, want: []string{}},
+		{args: []string{"-level", "ERROR", "-where", "user=42"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		cmd := parseCommand(append([]string{"-format", "json"}, tt.args...), &out)
+		if err := cmd.printer.print(strings.NewReader(input)); err != nil {
+			t.Fatalf("%q: %s", tt.args, err)
+		}
+		if got := messages(t, out.String()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: printed %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCommandArguments(t *testing.T) {
+	cmd := parseCommand([]string{"-f", "-verify", "-key-file", "key.txt", "a.log", "b.log"}, io.Discard)
+	if !cmd.follow || !cmd.verify || cmd.keyFile != "key.txt" || !reflect.DeepEqual(cmd.paths, []string{"a.log", "b.log"}) {
+		t.Fatalf("command = %+v, want -f and -verify set, key.txt as key file and 2 files", cmd)
+	}
+}
+
+func TestCommandFormats(t *testing.T) {
+	entries, input := sampleLogs()
+	tests := []struct {
+		format     string
+		serializer logs.Serializer
+	}{
+		{format: "auto", serializer: logs.AsJSON}, // Not printed to a terminal
+		{format: "json", serializer: logs.AsJSON},
+		{format: "text", serializer: logs.AsPlainText},
+		{format: "console", serializer: logs.AsConsole},
+	}
+	for _, tt := range tests {
+		var want strings.Builder
+		for _, l := range entries {
+			want.Write(tt.serializer(l))
+			want.WriteByte('\n')
+		}
+		var out bytes.Buffer
+		cmd := parseCommand([]string{"-format", tt.format}, &out)
+		if err := cmd.printer.print(strings.NewReader(input)); err != nil {
+			t.Fatalf("%s: %s", tt.format, err)
+		}
+		if out.String() != want.String() {
+			t.Errorf("%s: printed\n%s\nwant\n%s", tt.format, out.String(), want.String())
+		}
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	_, input := sampleLogs()
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: nil, want: []string{"server started", "slow request", "request failed"}},
+		{args: []string{"--since", "2020-01-02T11:00:00Z"}, want: []string{"slow request", "request failed"}},
+		{args: []string{"--until", "2020-01-02T11:00:00Z"}, want: []string{"server started"}},
+		{args: []string{"--since", "2020-01-02T10:30:00Z", "--until", "2020-01-02T11:30:00Z"}, want: []string{"slow request"}},
+		{args: []string{"--since", "1h"}, want: []string{}}, // The sample logs are older
+		{args: []string{"--level", "WARN"}, want: []string{"slow request", "request failed"}},
+		{args: []string{"--where", `data.user == "42"`}, want: []string{"slow request"}},
+		{args: []string{"--where", "data.user == 7"}, want: []string{"request failed"}},
+		{args: []string{"--where", "data.user", "--where", `message =~ "fail"`}, want: []string{"request failed"}},
+		{args: []string{"--where", `!data.user || level == "ERROR"`}, want: []string{"server started", "request failed"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		p, _ := parseQuery(append([]string{"--format", "json"}, tt.args...), &out)
+		if err := p.print(strings.NewReader(input)); err != nil {
+			t.Fatalf("%q: %s", tt.args, err)
+		}
+		if got := messages(t, out.String()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: printed %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ejuju/go-logs"
+)
+
+// expr is a boolean expression evaluated against logs (see parseExpr).
+type expr interface {
+	eval(l *logs.Log) bool
+}
+
+// parseExpr parses a query expression, for ex: `data.user_id == "42" && (level == "ERROR" || message =~ "time.?out")`.
+//
+// Comparisons have the form PATH OP VALUE, where PATH is "message", "level" (the level label),
+// "data.KEY" (nested values can be accessed with more dots, for ex: "data.request.method"),
+// OP is one of ==, !=, <, <=, >, >= or =~ (matches a regular expression)
+// and VALUE is a string (in double quotes), a number, true, false or null.
+// A path alone is true when the value exists. Comparisons can be combined with &&, || and ! and grouped with parentheses.
+//
+// Values of different types are compared as strings, so that `data.user_id == "42"` matches both 42 and "42".
+func parseExpr(s string) (expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return e, nil
+}
+
+// token is a lexical token of a query expression.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type tokenKind int
+
+const (
+	tokenPath tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+// Operators, longest first so that they are matched greedily.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+// tokenize splits a query expression into tokens.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			str, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+			}
+			tokens = append(tokens, token{tokenString, str})
+			i = end + 1
+		case c == '-' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || strings.ContainsRune(".eE+-", rune(s[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, s[i:end]})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(s) && (s[end] == '_' || s[end] == '.' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{tokenPath, s[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser of query expressions.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *parser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right expr
+		if right, err = p.and(); err == nil {
+			left = orExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var right expr
+		if right, err = p.unary(); err == nil {
+			left = andExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.accept("!") {
+		e, err := p.unary()
+		return notExpr{e}, err
+	}
+	if p.accept("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenPath {
+		return nil, fmt.Errorf("expected a path (for ex: data.user_id)")
+	}
+	path := p.tokens[p.pos].text
+	if path != "message" && path != "level" && !strings.HasPrefix(path, "data.") {
+		return nil, fmt.Errorf("unknown path %q (expected message, level or data.KEY)", path)
+	}
+	p.pos++
+
+	op := ""
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if p.accept(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return existsExpr{path}, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected a value after %s", op)
+	}
+	value, err := literal(p.tokens[p.pos])
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+
+	c := comparisonExpr{path: path, op: op, value: value}
+	if op == "=~" {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("=~ expects a string")
+		}
+		if c.re, err = regexp.Compile(s); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// literal returns the value of a literal token.
+func literal(t token) (any, error) {
+	switch {
+	case t.kind == tokenString:
+		return t.text, nil
+	case t.kind == tokenNumber:
+		return strconv.ParseFloat(t.text, 64)
+	case t.kind == tokenPath && t.text == "true":
+		return true, nil
+	case t.kind == tokenPath && t.text == "false":
+		return false, nil
+	case t.kind == tokenPath && t.text == "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("expected a value, got %q", t.text)
+}
+
+type orExpr struct{ left, right expr }
+
+func (e orExpr) eval(l *logs.Log) bool { return e.left.eval(l) || e.right.eval(l) }
+
+type andExpr struct{ left, right expr }
+
+func (e andExpr) eval(l *logs.Log) bool { return e.left.eval(l) && e.right.eval(l) }
+
+type notExpr struct{ e expr }
+
+func (e notExpr) eval(l *logs.Log) bool { return !e.e.eval(l) }
+
+type existsExpr struct{ path string }
+
+func (e existsExpr) eval(l *logs.Log) bool {
+	_, ok := lookup(l, e.path)
+	return ok
+}
+
+type comparisonExpr struct {
+	path  string
+	op    string
+	value any
+	re    *regexp.Regexp
+}
+
+func (e comparisonExpr) eval(l *logs.Log) bool {
+	v, ok := lookup(l, e.path)
+	if !ok {
+		return e.op == "!=" // Missing values are different from everything
+	}
+	if e.re != nil {
+		return e.re.MatchString(fmt.Sprint(v))
+	}
+
+	var cmp int
+	a, aIsNum := v.(float64)
+	b, bIsNum := e.value.(float64)
+	switch {
+	case aIsNum && bIsNum:
+		cmp = compareFloats(a, b)
+	case v == nil || e.value == nil:
+		if v != e.value {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(fmt.Sprint(v), fmt.Sprint(e.value))
+	}
+
+	switch e.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// lookup returns the value at the given path of a log.
+func lookup(l *logs.Log, path string) (any, bool) {
+	switch path {
+	case "message":
+		return l.Message, true
+	case "level":
+		v, ok := l.Data[logs.DataKeyLevel]
+		return v, ok
+	}
+	var v any = l.Data
+	for _, key := range strings.Split(strings.TrimPrefix(path, "data."), ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano), true // As written in the file
+	}
+	return v, true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ejuju/go-logs"
+)
+
+func TestParseExpr(t *testing.T) {
+	user := comparisonExpr{path: "data.user", op: "==", value: "42"}
+	errLevel := comparisonExpr{path: "level", op: "==", value: "ERROR"}
+	slow := comparisonExpr{path: "data.latency", op: ">", value: 1.5}
+	tests := []struct {
+		s    string
+		want expr
+	}{
+		{s: `data.user == "42"`, want: user},
+		{s: `data.user`, want: existsExpr{"data.user"}},
+		{s: `message != "ok"`, want: comparisonExpr{path: "message", op: "!=", value: "ok"}},
+		{s: `data.latency > 1.5`, want: slow},
+		{s: `data.delta <= -2e3`, want: comparisonExpr{path: "data.delta", op: "<=", value: -2000.0}},
+		{s: `data.request.method >= "GET"`, want: comparisonExpr{path: "data.request.method", op: ">=", value: "GET"}},
+		{s: `data.ok == true`, want: comparisonExpr{path: "data.ok", op: "==", value: true}},
+		{s: `data.ok < false`, want: comparisonExpr{path: "data.ok", op: "<", value: false}},
+		{s: `data.user == null`, want: comparisonExpr{path: "data.user", op: "==", value: nil}},
+		{s: `data.quote == "say \"hi\""`, want: comparisonExpr{path: "data.quote", op: "==", value: `say "hi"`}},
+
+		// Precedence: ! binds tighter than &&, which binds tighter than ||
+		{s: `data.user == "42" || level == "ERROR" && data.latency > 1.5`, want: orExpr{user, andExpr{errLevel, slow}}},
+		{s: `data.user == "42" && level == "ERROR" || data.latency > 1.5`, want: orExpr{andExpr{user, errLevel}, slow}},
+		{s: `(data.user == "42" || level == "ERROR") && data.latency > 1.5`, want: andExpr{orExpr{user, errLevel}, slow}},
+		{s: `!data.user == "42" && level == "ERROR"`, want: andExpr{notExpr{user}, errLevel}},
+		{s: `!(data.user == "42" && level == "ERROR")`, want: notExpr{andExpr{user, errLevel}}},
+		{s: `data.user == "42" || level == "ERROR" || data.latency > 1.5`, want: orExpr{orExpr{user, errLevel}, slow}},
+	}
+	for _, tt := range tests {
+		got, err := parseExpr(tt.s)
+		if err != nil {
+			t.Errorf("%s: %s", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsed %#v, want %#v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`data.user ==`,
+		`data.user == "42`,
+		`data.user == "\x"`,
+		`user == "42"`,
+		`data.user == data.other`,
+		`(data.user == "42"`,
+		`data.user == "42")`,
+		`data.user == "42" &&`,
+		`|| data.user`,
+		`!`,
+		`data.user =~ 42`,
+		`data.user =~ "("`,
+		`data.user @ "42"`,
+		`data.user "42"`,
+	}
+	for _, s := range tests {
+		if e, err := parseExpr(s); err == nil {
+			t.Errorf("%s: parsed %#v, want an error", s, e)
+		}
+	}
+}
+
+func TestEvalExpr(t *testing.T) {
+	l := logs.NewLog("request timeout", logs.WithLevel(logs.LevelError.String()), logs.WithData("user", 42.0),
+		logs.WithData("request", map[string]any{"method": "GET"}))
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: `data.user == "42"`, want: true}, // Compared as strings
+		{s: `data.user == 42`, want: true},
+		{s: `data.user > 100`, want: false},
+		{s: `data.request.method == "GET"`, want: true},
+		{s: `data.request.path`, want: false},
+		{s: `data.missing != "x"`, want: true}, // Missing values are different from everything
+		{s: `data.missing == null`, want: false},
+		{s: `message =~ "time.?out"`, want: true},
+		{s: `level == "ERROR" && !(message =~ "^ok")`, want: true},
+	}
+	for _, tt := range tests {
+		e, err := parseExpr(tt.s)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.s, err)
+		}
+		if got := e.eval(l); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
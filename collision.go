@@ -0,0 +1,97 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when a data key is set several times in a log
+// (for ex: with WithData in a base option and at the call site).
+//
+// Reserved keys (prefixed with "__", for ex: DataKeyLevel) are always protected,
+// whatever the policy: a value added with WithData (or another data option) under a reserved key
+// that is already set is stored under a suffixed key instead (for ex: "__level_2").
+// The options dedicated to reserved keys (for ex: WithLevel or WithTimestamp) still replace their value.
+type CollisionPolicy int
+
+const (
+	CollisionOverwrite CollisionPolicy = iota // The last value replaces the previous ones (the default)
+	CollisionError                            // Like CollisionOverwrite, but writing the log returns an error wrapping ErrKeyCollision
+	CollisionSuffix                           // Every value is kept: the first one under the key, the next ones under "key_2", "key_3"...
+)
+
+// ErrKeyCollision is wrapped by the errors returned when a data key is set several times in a log
+// with the CollisionError policy (the log is still written).
+var ErrKeyCollision = errors.New("data key collision")
+
+// collision records a data key set several times in a log.
+type collision struct {
+	key string
+	old any // Value replaced by the new one
+}
+
+// set adds a value to the data of the log (or its data, see Loggable), recording collisions (see CollisionPolicy).
+func (l *Log) set(key string, value any) {
+	value = logValue(value, 0)
+	old, exists := l.Data[key]
+	if !exists {
+		l.Data[key] = value
+		return
+	}
+	if strings.HasPrefix(key, dataKeyPrefix) {
+		l.Data[suffixedKey(l, key)] = value
+		return
+	}
+	l.Data[key] = value
+	l.collisions = append(l.collisions, collision{key: key, old: old})
+}
+
+// resolve applies the policy to the collisions recorded in the log.
+// It returns an error wrapping ErrKeyCollision with the CollisionError policy (if there are collisions).
+func (p CollisionPolicy) resolve(l *Log) error {
+	collisions := l.collisions
+	l.collisions = nil
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	// Group the replaced values by key, in the order they were set
+	values := map[string][]any{}
+	var keys []string
+	for _, c := range collisions {
+		if _, ok := values[c.key]; !ok {
+			keys = append(keys, c.key)
+		}
+		values[c.key] = append(values[c.key], c.old)
+	}
+
+	switch p {
+	case CollisionError:
+		quoted := make([]string, len(keys))
+		for i, k := range keys {
+			quoted[i] = strconv.Quote(k)
+		}
+		return fmt.Errorf("%w: %s", ErrKeyCollision, strings.Join(quoted, ", "))
+	case CollisionSuffix:
+		for _, k := range keys {
+			last := l.Data[k] // The last value set is the current one
+			l.Data[k] = values[k][0]
+			for _, v := range append(values[k][1:], last) {
+				l.Data[suffixedKey(l, k)] = v
+			}
+		}
+	}
+	return nil
+}
+
+// suffixedKey returns the first key that is not set in the log among "key_2", "key_3"...
+func suffixedKey(l *Log, key string) string {
+	for i := 2; ; i++ {
+		k := key + "_" + strconv.Itoa(i)
+		if _, exists := l.Data[k]; !exists {
+			return k
+		}
+	}
+}
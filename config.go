@@ -0,0 +1,207 @@
+package logs
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ejuju/go-logs/writers"
+)
+
+// Config holds declarative logger settings (for ex: loaded from a JSON file or environment variables),
+// so deployments can change logging behavior without recompiling. See Build.
+type Config struct {
+	Level      LogLevel       `json:"level"`       // For ex: "INFO" (all logs are written by default)
+	Format     string         `json:"format"`      // For ex: "json" (the default), "ordered", "pretty", "text", "console", "auto", "otel", "gelf", "cbor" or "journal"
+	Outputs    []OutputConfig `json:"outputs"`     // For ex: stdout and a rotated file (stdout by default)
+	Timestamp  bool           `json:"timestamp"`   // For ex: true to add the creation time to logs
+	Source     bool           `json:"source"`      // For ex: true to add the source code location to logs
+	Async      bool           `json:"async"`       // For ex: true to write logs from a background goroutine
+	BufferSize int            `json:"buffer_size"` // For ex: 1024 logs queued at most in async mode
+}
+
+// OutputConfig configures an output of a logger built from a Config.
+type OutputConfig struct {
+	Path     string          `json:"path"`     // For ex: "stdout", "stderr" or "/var/log/app.log"
+	Format   string          `json:"format"`   // For ex: "console" (defaults to the format of the logger)
+	Level    LogLevel        `json:"level"`    // For ex: "ERROR" to only write errors to this output
+	Rotation *RotationConfig `json:"rotation"` // For ex: rotate the file every day (files only)
+}
+
+// RotationConfig configures the rotation of a file output (see writers.RotatingFile).
+type RotationConfig struct {
+	MaxBytes int64  `json:"max_bytes"` // For ex: 104857600 (100 MB)
+	MaxAge   string `json:"max_age"`   // For ex: "24h" (parsed with time.ParseDuration)
+	MaxFiles int    `json:"max_files"` // For ex: 7
+	Compress bool   `json:"compress"`  // For ex: true to gzip rotated files
+}
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvConfigFile = "LOGS_CONFIG"    // For ex: "/etc/app/logs.json" (loaded first, other variables override it)
+	EnvLevel      = "LOGS_LEVEL"     // For ex: "WARN"
+	EnvFormat     = "LOGS_FORMAT"    // For ex: "console"
+	EnvOutputs    = "LOGS_OUTPUTS"   // For ex: "stdout,/var/log/app.log" (comma-separated paths)
+	EnvTimestamp  = "LOGS_TIMESTAMP" // For ex: "true"
+	EnvSource     = "LOGS_SOURCE"    // For ex: "true"
+	EnvAsync      = "LOGS_ASYNC"     // For ex: "true"
+)
+
+// ConfigFromFile reads a JSON config file.
+func ConfigFromFile(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ConfigFromEnv returns the config described by the LOGS_* environment variables (see EnvLevel and others).
+func ConfigFromEnv() (Config, error) {
+	var c Config
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		var err error
+		if c, err = ConfigFromFile(path); err != nil {
+			return c, err
+		}
+	}
+	if v := os.Getenv(EnvLevel); v != "" {
+		if err := c.Level.UnmarshalText([]byte(v)); err != nil {
+			return c, fmt.Errorf("%s: %w", EnvLevel, err)
+		}
+	}
+	if v := os.Getenv(EnvFormat); v != "" {
+		c.Format = v
+	}
+	if v := os.Getenv(EnvOutputs); v != "" {
+		c.Outputs = nil
+		for _, path := range strings.Split(v, ",") {
+			c.Outputs = append(c.Outputs, OutputConfig{Path: strings.TrimSpace(path)})
+		}
+	}
+	for name, dst := range map[string]*bool{EnvTimestamp: &c.Timestamp, EnvSource: &c.Source, EnvAsync: &c.Async} {
+		if v := os.Getenv(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return c, fmt.Errorf("%s: %w", name, err)
+			}
+			*dst = b
+		}
+	}
+	return c, nil
+}
+
+// RegisterFlags registers command-line flags overriding the config (for ex: -log-level=debug),
+// their default values are the current settings.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.Func("log-level", "minimum level of the logs to write (for ex: INFO)", func(v string) error {
+		return c.Level.UnmarshalText([]byte(v))
+	})
+	fs.StringVar(&c.Format, "log-format", c.Format, "format of the logs (json, ordered, pretty, text, console, auto, otel, gelf or cbor)")
+	fs.BoolVar(&c.Timestamp, "log-timestamp", c.Timestamp, "add the creation time to logs")
+	fs.BoolVar(&c.Source, "log-source", c.Source, "add the source code location to logs")
+}
+
+// Build returns a logger configured with the settings, opening the outputs.
+// Each output is a sink of the returned logger, so it gets closed by DefaultLogger.Close.
+func (c Config) Build() (*DefaultLogger, error) {
+	dl := &DefaultLogger{Async: c.Async, BufferSize: c.BufferSize}
+	dl.SetMinLevel(c.Level)
+	if c.Timestamp {
+		dl.BaseOptions = append(dl.BaseOptions, WithTimestamp())
+	}
+	if c.Source {
+		dl.BaseOptions = append(dl.BaseOptions, WithSrc())
+	}
+
+	outputs := c.Outputs
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Path: "stdout"}}
+	}
+	for _, out := range outputs {
+		format := out.Format
+		if format == "" {
+			format = c.Format
+		}
+		if _, err := serializerFor(format, io.Discard); err != nil {
+			dl.Close()
+			return nil, err
+		}
+		w, err := out.open()
+		if err != nil {
+			dl.Close()
+			return nil, err
+		}
+		serializer, _ := serializerFor(format, w)
+		dl.Sinks = append(dl.Sinks, Sink{Writer: w, Serializer: serializer, MinLevel: out.Level})
+	}
+	dl.Serializer = dl.Sinks[0].Serializer
+	return dl, nil
+}
+
+// open opens the output.
+func (out OutputConfig) open() (io.Writer, error) {
+	switch out.Path {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	if out.Rotation == nil {
+		if err := os.MkdirAll(filepath.Dir(out.Path), 0o755); err != nil {
+			return nil, err
+		}
+		return os.OpenFile(out.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+
+	var maxAge time.Duration
+	if out.Rotation.MaxAge != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(out.Rotation.MaxAge); err != nil {
+			return nil, fmt.Errorf("rotation max age of %s: %w", out.Path, err)
+		}
+	}
+	rf, err := writers.NewRotatingFile(out.Path)
+	if err != nil {
+		return nil, err
+	}
+	rf.MaxBytes, rf.MaxAge, rf.MaxFiles, rf.Compress = out.Rotation.MaxBytes, maxAge, out.Rotation.MaxFiles, out.Rotation.Compress
+	return rf, nil
+}
+
+// serializerFor returns the serializer with the given name, "auto" depends on whether w is a terminal.
+func serializerFor(format string, w io.Writer) (Serializer, error) {
+	switch format {
+	case "", "json":
+		return AsJSON, nil
+	case "ordered":
+		return AsOrderedJSON, nil
+	case "pretty":
+		return AsPrettyJSON, nil
+	case "text":
+		return AsPlainText, nil
+	case "console":
+		return AsConsole, nil
+	case "auto":
+		return AsConsoleFor(w), nil
+	case "otel":
+		return AsOTelJSON, nil
+	case "gelf":
+		return AsGELF, nil
+	case "cbor":
+		return AsCBOR, nil
+	case "journal":
+		return AsJournal, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ANSI escape codes used by the console serializer.
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// Holds the color of each log level in the console.
+var levelColors = map[LogLevel]string{
+	LevelUnknown: "\x1b[37m",   // white
+	LevelTrace:   "\x1b[2m",    // dim
+	LevelDebug:   "\x1b[90m",   // gray
+	LevelInfo:    "\x1b[32m",   // green
+	LevelWarn:    "\x1b[33m",   // yellow
+	LevelError:   "\x1b[31m",   // red
+	LevelPanic:   "\x1b[35m",   // magenta
+	LevelFatal:   "\x1b[1;35m", // bold magenta
+}
+
+// Returns a human-readable, ANSI-colored, single-line representation of a log,
+// meant to be read by developers in a terminal.
+// The timestamp (dimmed) and level (colored) come first, followed by the message and the remaining data (sorted by key).
+func AsConsole(l *Log) []byte {
+	sb := &strings.Builder{}
+
+	if t, ok := l.Data[DataKeyTimestamp].(time.Time); ok {
+		ts := t.Format("15:04:05.000")
+		if l.timeFormat != nil {
+			ts = fmt.Sprint(l.formatTime(t))
+		}
+		sb.WriteString(ansiDim + ts + ansiReset + " ")
+	}
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok {
+		sb.WriteString(levelColors[baseLevel(lvl)] + fmt.Sprintf("%-5s", lvl) + ansiReset + " ")
+	}
+	fmt.Fprintf(sb, "%-32s", l.Message)
+
+	for _, k := range sortedDataKeys(l) {
+		if k == DataKeyTimestamp || k == DataKeyLevel {
+			continue
+		}
+		sb.WriteString(" " + ansiCyan + k + ansiReset + "=" + consoleValue(l, k))
+	}
+	return []byte(strings.TrimRight(sb.String(), " "))
+}
+
+// consoleValue formats a data value of a log for the console, quoting strings when needed.
+func consoleValue(l *Log, k string) string {
+	s := l.textValue(k, stringify(l.Data[k], false))
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// AsConsoleFor returns AsConsole if w is a terminal and AsJSON otherwise,
+// so that developers get readable output locally while files and pipes get JSON.
+func AsConsoleFor(w io.Writer) Serializer {
+	if IsTerminal(w) {
+		return AsConsole
+	}
+	return AsJSON
+}
+
+// IsTerminal reports whether w is a terminal (character device).
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"context"
+	"sync"
+)
+
+// DataKeyRequestID is the data key under which request IDs are stored.
+const DataKeyRequestID = "request_id"
+
+// contextKey is used to store values in a context.Context without colliding with other packages.
+type contextKey int
+
+const (
+	contextKeyRequestID contextKey = iota
+	contextKeySpan
+)
+
+// WithRequestID adds a request ID to the log.
+func WithRequestID(id string) LogOption { return func(l *Log) { l.Data[DataKeyRequestID] = id } }
+
+// ContextWithRequestID returns a copy of the context holding the given request ID.
+// This is typically used by HTTP middlewares so that the ID can be retrieved later on.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
+
+// RequestIDFromContext returns the request ID stored in the context (if any).
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKeyRequestID).(string)
+	return id, ok
+}
+
+// contextField extracts a value from a context to store it in logs.
+type contextField struct {
+	key     string
+	extract func(context.Context) any
+}
+
+// Holds the registered context fields, the request ID and the IDs of the span (see ContextWithSpan) are registered by default.
+var (
+	contextFieldsMu sync.RWMutex
+	contextFields   = []contextField{
+		{key: DataKeyRequestID, extract: func(ctx context.Context) any {
+			if id, ok := RequestIDFromContext(ctx); ok {
+				return id
+			}
+			return nil
+		}},
+		{key: DataKeySpanID, extract: func(ctx context.Context) any {
+			if sp, ok := SpanFromContext(ctx); ok {
+				return sp.id
+			}
+			return nil
+		}},
+		{key: DataKeyTraceID, extract: func(ctx context.Context) any {
+			if sp, ok := SpanFromContext(ctx); ok {
+				return sp.traceID
+			}
+			return nil
+		}},
+	}
+)
+
+// RegisterContextField registers a function extracting a value from a context (for ex: a trace ID or user ID).
+// The value is added under the given key to logs created with WithContext, unless it is nil.
+// Registering a key again replaces its previous extractor.
+func RegisterContextField(key string, extract func(ctx context.Context) any) {
+	contextFieldsMu.Lock()
+	defer contextFieldsMu.Unlock()
+	for i, f := range contextFields {
+		if f.key == key {
+			contextFields[i].extract = extract
+			return
+		}
+	}
+	contextFields = append(contextFields, contextField{key: key, extract: extract})
+}
+
+// WithContext adds the values extracted from the context by the registered context fields to the log.
+func WithContext(ctx context.Context) LogOption {
+	return func(l *Log) {
+		contextFieldsMu.RLock()
+		defer contextFieldsMu.RUnlock()
+		for _, f := range contextFields {
+			if v := f.extract(ctx); v != nil {
+				l.Data[f.key] = logValue(v, 0)
+			}
+		}
+	}
+}
+
+// ContextLoggerFunc writes a log, adding the values extracted from the context by the registered context fields.
+type ContextLoggerFunc func(context.Context, *Log) error
+
+// ContextLoggerFunc returns a function that writes logs with the values extracted from the given contexts.
+func (dl *DefaultLogger) ContextLoggerFunc() (ContextLoggerFunc, error) {
+	fn, err := dl.LoggerFunc()
+	if err != nil {
+		return nil, err
+	}
+	return fn.WithContext, nil
+}
+
+// WithContext writes a log, adding the values extracted from the context by the registered context fields.
+func (fn LoggerFunc) WithContext(ctx context.Context, l *Log) error {
+	WithContext(ctx)(l)
+	return fn(l)
+}
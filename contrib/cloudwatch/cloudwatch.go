@@ -0,0 +1,275 @@
+// Package cloudwatchlogs provides a writer sending logs to Amazon CloudWatch Logs,
+// so that services running on AWS don't need a separate agent to ship their logs.
+package cloudwatchlogs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	logs "github.com/ejuju/go-logs"
+)
+
+// Client is the part of the CloudWatch Logs API used by Writer, it is implemented by *cloudwatchlogs.Client.
+type Client interface {
+	CreateLogGroup(ctx context.Context, params *cloudwatchlogs.CreateLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	CreateLogStream(ctx context.Context, params *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+// Limits of a PutLogEvents call.
+const (
+	maxBatchEvents = 10_000
+	maxBatchBytes  = 1_048_576
+	maxBatchSpan   = 24 * time.Hour
+	eventOverhead  = 26 // Bytes counted for each event in addition to its message
+	maxEventBytes  = maxBatchBytes - eventOverhead
+)
+
+// ErrEventTooLarge is returned when a log is too large to fit in a CloudWatch Logs event.
+var ErrEventTooLarge = errors.New("log is too large for a CloudWatch Logs event")
+
+// Writer sends logs to a CloudWatch Logs stream, each log being an event.
+// Events are sent in batches, when a batch is full or every flush interval.
+// It implements logs.LogWriter, so that the timestamp of events is the creation time of logs (when available).
+type Writer struct {
+	client        Client
+	group, stream string
+	flushInterval time.Duration
+	maxRetries    int
+	timeout       time.Duration
+
+	mu      sync.Mutex
+	events  []types.InputLogEvent
+	size    int     // Size of the current batch, as counted by CloudWatch Logs
+	token   *string // Sequence token expected by the stream (ignored by recent versions of the API)
+	err     error   // Error of the last background flush, returned by the next call
+	closed  bool
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Option configures a Writer.
+type Option func(*Writer)
+
+// WithFlushInterval sets how often the current batch is sent (5 seconds by default, 0 means batches are only sent when full).
+func WithFlushInterval(d time.Duration) Option { return func(w *Writer) { w.flushInterval = d } }
+
+// WithMaxRetries sets how many times a batch is sent again when the request is throttled (5 by default).
+// Retries are spaced with an exponential backoff, starting at 200ms.
+func WithMaxRetries(n int) Option { return func(w *Writer) { w.maxRetries = n } }
+
+// WithTimeout sets the timeout of each request to CloudWatch Logs (10 seconds by default).
+func WithTimeout(d time.Duration) Option { return func(w *Writer) { w.timeout = d } }
+
+// New returns a writer sending logs to the given log group and stream, both are created if they don't exist yet.
+// The client is typically created with cloudwatchlogs.NewFromConfig.
+func New(ctx context.Context, client Client, group, stream string, opts ...Option) (*Writer, error) {
+	w := &Writer{
+		client:        client,
+		group:         group,
+		stream:        stream,
+		flushInterval: 5 * time.Second,
+		maxRetries:    5,
+		timeout:       10 * time.Second,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	_, err := client.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(group)})
+	if err != nil && !isAlreadyExists(err) {
+		return nil, err
+	}
+	_, err = client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+	})
+	if err != nil && !isAlreadyExists(err) {
+		return nil, err
+	}
+
+	if w.flushInterval <= 0 {
+		close(w.stopped)
+		return w, nil
+	}
+	go func() {
+		defer close(w.stopped)
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				w.mu.Lock()
+				if err := w.flush(); err != nil {
+					w.err = err
+				}
+				w.mu.Unlock()
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Write adds b (without its trailing line break) to the current batch, with the current time as timestamp.
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.add(time.Now(), b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// WriteLog adds a serialized log to the current batch, with the creation time of the log as timestamp
+// (or the current time if the log has no timestamp).
+func (w *Writer) WriteLog(l *logs.Log, b []byte) error {
+	t, ok := l.Data[logs.DataKeyTimestamp].(time.Time)
+	if !ok {
+		t = time.Now()
+	}
+	return w.add(t, b)
+}
+
+// add adds an event to the current batch, sending the batch first if the event doesn't fit in.
+// An error of a previous background flush is returned (once) if there was one.
+func (w *Writer) add(t time.Time, b []byte) error {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	if len(b) > maxEventBytes {
+		return ErrEventTooLarge
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	if len(w.events) >= maxBatchEvents || w.size+len(b)+eventOverhead > maxBatchBytes {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.events = append(w.events, types.InputLogEvent{
+		Message:   aws.String(string(b)),
+		Timestamp: aws.Int64(t.UnixMilli()),
+	})
+	w.size += len(b) + eventOverhead
+	return nil
+}
+
+// Flush sends the current batch.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// Close stops the background flushes and sends the current batch.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	<-w.stopped
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.takeErr()
+	if ferr := w.flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// flush sends the current batch, it must be called while holding the lock.
+// The batch is dropped if it cannot be sent, so a failing stream doesn't make it grow forever.
+func (w *Writer) flush() error {
+	if len(w.events) == 0 {
+		return nil
+	}
+	events := w.events
+	w.events, w.size = nil, 0
+
+	// Events of a batch must be in chronological order and span at most 24 hours
+	sort.SliceStable(events, func(i, j int) bool { return *events[i].Timestamp < *events[j].Timestamp })
+	var err error
+	for len(events) > 0 {
+		n := 1
+		for n < len(events) && time.Duration(*events[n].Timestamp-*events[0].Timestamp)*time.Millisecond < maxBatchSpan {
+			n++
+		}
+		if perr := w.put(events[:n]); err == nil {
+			err = perr
+		}
+		events = events[n:]
+	}
+	return err
+}
+
+// put sends events, retrying when the request is throttled or when the sequence token is outdated.
+func (w *Writer) put(events []types.InputLogEvent) error {
+	backoff := 200 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+		out, err := w.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(w.group),
+			LogStreamName: aws.String(w.stream),
+			LogEvents:     events,
+			SequenceToken: w.token,
+		})
+		cancel()
+		if err == nil {
+			w.token = out.NextSequenceToken
+			return nil
+		}
+
+		var invalidToken *types.InvalidSequenceTokenException
+		var alreadyAccepted *types.DataAlreadyAcceptedException
+		var throttled *types.ThrottlingException
+		switch {
+		case errors.As(err, &alreadyAccepted):
+			w.token = alreadyAccepted.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &invalidToken) && attempt < w.maxRetries:
+			w.token = invalidToken.ExpectedSequenceToken
+		case errors.As(err, &throttled) && attempt < w.maxRetries:
+			time.Sleep(backoff)
+			backoff *= 2
+		default:
+			return err
+		}
+	}
+}
+
+// takeErr returns and clears the error of the last background flush.
+func (w *Writer) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// isAlreadyExists reports whether err means that the log group or stream already exists.
+func isAlreadyExists(err error) bool {
+	var exists *types.ResourceAlreadyExistsException
+	return errors.As(err, &exists)
+}
@@ -0,0 +1,20 @@
+module github.com/ejuju/go-logs/contrib/cloudwatch
+
+// Go 1.24 is required by the AWS SDK (the logs module itself requires Go 1.18).
+go 1.24
+
+// No version of the logs module has been tagged yet: the version required below is an untagged commit
+// that is not available from the module proxy, so this module only builds within the workspace of contrib/go.work
+// (which replaces it with the local module) until a release is tagged and required here.
+require (
+	github.com/aws/aws-sdk-go-v2 v1.47.1
+	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.88.1
+	github.com/ejuju/go-logs v0.0.0-20261015013608-5ddb925de917
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.7.20 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.5.4 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.8.4 // indirect
+	github.com/aws/smithy-go v1.28.1 // indirect
+)
@@ -0,0 +1,20 @@
+module github.com/ejuju/go-logs/contrib/grpc
+
+// Go 1.25.0 is required by google.golang.org/grpc (the logs module itself requires Go 1.18).
+go 1.25.0
+
+// No version of the logs module has been tagged yet: the version required below is an untagged commit
+// that is not available from the module proxy, so this module only builds within the workspace of contrib/go.work
+// (which replaces it with the local module) until a release is tagged and required here.
+require (
+	github.com/ejuju/go-logs v0.0.0-20261015013608-5ddb925de917
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+)
@@ -0,0 +1,176 @@
+// Package grpclogs provides gRPC server interceptors writing a log for each request or stream.
+package grpclogs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	logs "github.com/ejuju/go-logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Data keys used by the interceptors.
+const (
+	DataKeyMethod   = "grpc_method"
+	DataKeyCode     = "grpc_code"
+	DataKeyLatency  = "latency"
+	DataKeyPeer     = "peer"
+	DataKeyRequest  = "grpc_request"
+	DataKeyResponse = "grpc_response"
+)
+
+// Option configures the interceptors.
+type Option func(*config)
+
+type config struct {
+	payloads bool
+	redactor logs.Redactor
+	recovery bool
+}
+
+// WithPayloads adds the request and response messages of unary calls to the logs
+// (encoded like their protobuf JSON representation).
+func WithPayloads() Option { return func(c *config) { c.payloads = true } }
+
+// WithRedactor masks sensitive data in the logs before they are written, for ex: a logs.FieldRedactor
+// masking payload fields named "password".
+func WithRedactor(r logs.Redactor) Option { return func(c *config) { c.redactor = r } }
+
+// WithRecovery makes the interceptors recover from panics of the handlers:
+// the call is logged at panic level with the recovered panic (see logs.WithRecovered)
+// and fails with an Internal status.
+func WithRecovery() Option { return func(c *config) { c.recovery = true } }
+
+// UnaryServerInterceptor returns an interceptor that writes a log for each unary call
+// with its method, status code, latency and peer address.
+// Calls resulting in a server error (for ex: codes.Internal or codes.Unavailable) are logged at error level,
+// others at info level.
+// Values extracted from the call context by the registered context fields are also added (see logs.WithContext).
+func UnaryServerInterceptor(log logs.LoggerFunc, opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		var recovered logs.LogOption
+		resp, err := func() (resp any, err error) {
+			defer c.recoverInto(&err, &recovered)
+			return handler(ctx, req)
+		}()
+
+		l := newLog(ctx, info.FullMethod, start, err)
+		withRecovered(l, recovered)
+		if c.payloads {
+			l.Data[DataKeyRequest] = payload(req)
+			if err == nil {
+				l.Data[DataKeyResponse] = payload(resp)
+			}
+		}
+		c.write(log, l)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that writes a log for each stream once it ends
+// with its method, status code, latency and peer address (see UnaryServerInterceptor).
+func StreamServerInterceptor(log logs.LoggerFunc, opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		var recovered logs.LogOption
+		err := func() (err error) {
+			defer c.recoverInto(&err, &recovered)
+			return handler(srv, ss)
+		}()
+
+		l := newLog(ss.Context(), info.FullMethod, start, err)
+		withRecovered(l, recovered)
+		c.write(log, l)
+		return err
+	}
+}
+
+// newConfig applies the given options.
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// recoverInto recovers from a panic of a handler if recovery is enabled,
+// making the call fail with an Internal status and storing the recovered panic (see logs.WithRecovered).
+// It must be deferred directly.
+func (c *config) recoverInto(err *error, recovered *logs.LogOption) {
+	if !c.recovery {
+		return
+	}
+	if v := recover(); v != nil {
+		*err = status.Error(codes.Internal, "internal error")
+		*recovered = logs.WithRecovered(v)
+	}
+}
+
+// withRecovered adds the recovered panic (if any) to the log of a call, at panic level.
+func withRecovered(l *logs.Log, recovered logs.LogOption) {
+	if recovered == nil {
+		return
+	}
+	logs.WithLevel(logs.LevelPanic.String())(l)
+	recovered(l)
+}
+
+// write masks sensitive data (if enabled) and writes the log, the call is not affected by write errors.
+func (c *config) write(log logs.LoggerFunc, l *logs.Log) {
+	if c.redactor != nil {
+		c.redactor.Redact(l)
+	}
+	log(l)
+}
+
+// newLog returns a log describing a call.
+func newLog(ctx context.Context, method string, start time.Time, err error) *logs.Log {
+	code := status.Code(err)
+	lvl := logs.LevelInfo
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		lvl = logs.LevelError
+	}
+	opts := []logs.LogOption{
+		logs.WithLevel(lvl.String()),
+		logs.WithContext(ctx),
+		logs.WithData(DataKeyMethod, method),
+		logs.WithData(DataKeyCode, code.String()),
+		logs.WithData(DataKeyLatency, time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		opts = append(opts, logs.WithData(DataKeyPeer, p.Addr.String()))
+	}
+	if err != nil {
+		opts = append(opts, logs.WithError(err))
+	}
+	return logs.NewLog(method, opts...)
+}
+
+// payload returns the representation of a message to store in a log.
+// Protobuf messages are converted to generic maps so that their fields can be redacted.
+func payload(msg any) any {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return msg
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err.Error()
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err.Error()
+	}
+	return v
+}
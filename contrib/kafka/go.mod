@@ -0,0 +1,17 @@
+module github.com/ejuju/go-logs/contrib/kafka
+
+// Go 1.23 is required by github.com/segmentio/kafka-go (the logs module itself requires Go 1.18).
+go 1.23
+
+// No version of the logs module has been tagged yet: the version required below is an untagged commit
+// that is not available from the module proxy, so this module only builds within the workspace of contrib/go.work
+// (which replaces it with the local module) until a release is tagged and required here.
+require (
+	github.com/ejuju/go-logs v0.0.0-20261015013608-5ddb925de917
+	github.com/segmentio/kafka-go v0.4.51
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
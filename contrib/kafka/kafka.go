@@ -0,0 +1,83 @@
+// Package kafkalogs provides a writer publishing logs to Apache Kafka.
+package kafkalogs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	logs "github.com/ejuju/go-logs"
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer publishes each log as a Kafka message.
+// It implements logs.LogWriter, so that the message key can be taken from a log field.
+type Writer struct {
+	w        *kafka.Writer
+	keyField string
+}
+
+// Option configures a Writer.
+type Option func(*Writer)
+
+// WithKeyField uses the value of the given data key as the message key (for ex: "request_id"),
+// so that related logs end up in the same partition. Messages without this field have no key.
+func WithKeyField(key string) Option { return func(w *Writer) { w.keyField = key } }
+
+// WithDeliveryHook makes writes asynchronous (they don't wait for the brokers to acknowledge messages)
+// and reports deliveries to the hook: AfterWrite is called once per message with the delivery error (if any),
+// from a goroutine of the underlying Kafka writer.
+func WithDeliveryHook(h logs.Hook) Option {
+	return func(w *Writer) {
+		w.w.Async = true
+		w.w.Completion = func(messages []kafka.Message, err error) {
+			for _, m := range messages {
+				l, _ := m.WriterData.(*logs.Log)
+				h.AfterWrite(l, m.Value, err)
+			}
+		}
+	}
+}
+
+// WithKafkaWriter configures the underlying Kafka writer (for ex: batching, compression or TLS).
+func WithKafkaWriter(configure func(*kafka.Writer)) Option {
+	return func(w *Writer) { configure(w.w) }
+}
+
+// New returns a writer publishing messages to the given topic.
+func New(brokers []string, topic string, opts ...Option) *Writer {
+	w := &Writer{w: &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{}, // Same key, same partition
+	}}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Write publishes b (without its trailing line break) as a message without key.
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.publish(nil, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// WriteLog publishes a serialized log, with the value of the key field (if any) as the message key.
+func (w *Writer) WriteLog(l *logs.Log, b []byte) error { return w.publish(l, b) }
+
+// publish publishes a message.
+func (w *Writer) publish(l *logs.Log, b []byte) error {
+	m := kafka.Message{Value: append([]byte(nil), bytes.TrimSuffix(b, []byte("\n"))...), WriterData: l}
+	if l != nil && w.keyField != "" {
+		if v, ok := l.Data[w.keyField]; ok {
+			m.Key = []byte(fmt.Sprint(v))
+		}
+	}
+	return w.w.WriteMessages(context.Background(), m)
+}
+
+// Close flushes pending messages and closes the underlying Kafka writer.
+func (w *Writer) Close() error { return w.w.Close() }
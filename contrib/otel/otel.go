@@ -0,0 +1,58 @@
+// Package otellogs correlates logs with OpenTelemetry traces.
+//
+// Trace and span IDs are stored under logs.DataKeyTraceID and logs.DataKeySpanID ("__trace_id" and "__span_id"),
+// the keys of the spans of the logs package, instead of "trace_id" and "span_id" as in the first version of this package,
+// so that logs are correlated on the same keys whether they are traced with OpenTelemetry or with logs.Span.
+package otellogs
+
+import (
+	"context"
+
+	logs "github.com/ejuju/go-logs"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Data keys under which trace and span IDs are stored, the same as the ones of the spans of the logs package
+// so that logs can be correlated whichever way they are traced.
+const (
+	DataKeyTraceID = logs.DataKeyTraceID
+	DataKeySpanID  = logs.DataKeySpanID
+)
+
+// WithOTelTrace adds the trace and span IDs of the span active in the context to the log (if any),
+// so logs can be correlated with traces in backends like Tempo or Jaeger.
+func WithOTelTrace(ctx context.Context) logs.LogOption {
+	return func(l *logs.Log) {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return
+		}
+		l.Data[DataKeyTraceID] = sc.TraceID().String()
+		l.Data[DataKeySpanID] = sc.SpanID().String()
+	}
+}
+
+// RegisterContextFields registers the trace and span IDs as context fields (see logs.RegisterContextField),
+// so they are automatically added to logs written with logs.WithContext or a logs.ContextLoggerFunc.
+// They replace the context fields of the spans of the logs package, which are still used for contexts
+// without a valid OpenTelemetry span (see logs.ContextWithSpan).
+func RegisterContextFields() {
+	logs.RegisterContextField(DataKeyTraceID, func(ctx context.Context) any {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			return sc.TraceID().String()
+		}
+		if sp, ok := logs.SpanFromContext(ctx); ok {
+			return sp.TraceID()
+		}
+		return nil
+	})
+	logs.RegisterContextField(DataKeySpanID, func(ctx context.Context) any {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			return sc.SpanID().String()
+		}
+		if sp, ok := logs.SpanFromContext(ctx); ok {
+			return sp.ID()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,31 @@
+package otellogs
+
+import (
+	"context"
+	"testing"
+
+	logs "github.com/ejuju/go-logs"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRegisterContextFields(t *testing.T) {
+	RegisterContextFields()
+
+	// Spans of the logs package are still logged
+	fn := logs.LoggerFunc(func(l *logs.Log) error { return nil })
+	sp, ctx := fn.StartSpan(context.Background(), "op")
+	l := logs.NewLog("msg", logs.WithContext(ctx))
+	if l.Data[DataKeySpanID] != sp.ID() || l.Data[DataKeyTraceID] != sp.TraceID() {
+		t.Fatalf("data = %v, want the IDs of the span of the logs package", l.Data)
+	}
+
+	// OpenTelemetry spans take precedence
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	l = logs.NewLog("msg", logs.WithContext(trace.ContextWithSpanContext(ctx, sc)))
+	if l.Data[DataKeySpanID] != sc.SpanID().String() || l.Data[DataKeyTraceID] != sc.TraceID().String() {
+		t.Fatalf("data = %v, want the IDs of the OpenTelemetry span", l.Data)
+	}
+}
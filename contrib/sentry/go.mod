@@ -0,0 +1,17 @@
+module github.com/ejuju/go-logs/contrib/sentry
+
+// Go 1.25.0 is required by github.com/getsentry/sentry-go (the logs module itself requires Go 1.18).
+go 1.25.0
+
+// No version of the logs module has been tagged yet: the version required below is an untagged commit
+// that is not available from the module proxy, so this module only builds within the workspace of contrib/go.work
+// (which replaces it with the local module) until a release is tagged and required here.
+require (
+	github.com/ejuju/go-logs v0.0.0-20261015013608-5ddb925de917
+	github.com/getsentry/sentry-go v0.49.0
+)
+
+require (
+	golang.org/x/sys v0.46.0 // indirect
+	golang.org/x/text v0.39.0 // indirect
+)
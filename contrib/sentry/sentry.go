@@ -0,0 +1,236 @@
+// Package sentrylogs provides a hook forwarding error logs to Sentry.
+package sentrylogs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logs "github.com/ejuju/go-logs"
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrFlushTimeout is returned by Flush and Close when events are still waiting to be sent after the flush timeout.
+var ErrFlushTimeout = errors.New("sentry: flush timed out")
+
+// Hook sends the logs written by a logger at error level or above to Sentry, as events:
+//
+//	hook, err := sentrylogs.New(os.Getenv("SENTRY_DSN"))
+//	dl := &logs.DefaultLogger{Writers: []io.Writer{os.Stdout}, Serializer: logs.AsJSON, Hooks: []logs.Hook{hook}}
+//
+// The log message is the title of the event, its error or panic (see logs.WithError and logs.WithRecovered)
+// is the exception, with the stack trace of the log (see logs.WithStack or DefaultLogger.StackLevel).
+// The fingerprint of the log (see logs.WithFingerprint) is used to group events,
+// its request, trace and span IDs are added as tags and its data as context.
+//
+// Events are sent in the background by the Sentry client, they are sent after redaction (see DefaultLogger.Redactor).
+// The hook is flushed and closed with the logger (see DefaultLogger.Close), so that pending events are not lost.
+type Hook struct {
+	client   *sentry.Client
+	options  sentry.ClientOptions
+	minLevel logs.LogLevel
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	last *logs.Log // Last log sent, AfterWrite is called once per output
+}
+
+// Option configures a Hook.
+type Option func(*Hook)
+
+// WithMinLevel sends the logs with the given level or above (logs.LevelError by default).
+func WithMinLevel(lvl logs.LogLevel) Option { return func(h *Hook) { h.minLevel = lvl } }
+
+// WithFlushTimeout sets how long Flush and Close wait for pending events to be sent (2s by default).
+func WithFlushTimeout(d time.Duration) Option { return func(h *Hook) { h.timeout = d } }
+
+// WithClientOptions configures the Sentry client (for ex: its environment, release or sample rate).
+func WithClientOptions(configure func(*sentry.ClientOptions)) Option {
+	return func(h *Hook) { configure(&h.options) }
+}
+
+// New returns a hook sending events to the project with the given DSN.
+// An empty DSN disables sending (events are dropped), for ex: in development.
+func New(dsn string, opts ...Option) (*Hook, error) {
+	h := &Hook{options: sentry.ClientOptions{Dsn: dsn}, minLevel: logs.LevelError, timeout: 2 * time.Second}
+	for _, opt := range opts {
+		opt(h)
+	}
+	client, err := sentry.NewClient(h.options)
+	if err != nil {
+		return nil, fmt.Errorf("create sentry client: %w", err)
+	}
+	h.client = client
+	return h, nil
+}
+
+// BeforeSerialize does nothing, logs are sent once written (see AfterWrite).
+func (h *Hook) BeforeSerialize(l *logs.Log) {}
+
+// AfterWrite sends the log to Sentry if its level is high enough, once per log (whatever the number of outputs).
+func (h *Hook) AfterWrite(l *logs.Log, b []byte, err error) {
+	lvl, ok := levelOf(l)
+	if !ok || lvl < h.minLevel {
+		return
+	}
+	h.mu.Lock()
+	if h.last == l {
+		h.mu.Unlock()
+		return
+	}
+	h.last = l
+	h.mu.Unlock()
+	h.client.CaptureEvent(Event(l), nil, nil)
+}
+
+// Flush waits for pending events to be sent.
+func (h *Hook) Flush() error {
+	if !h.client.Flush(h.timeout) {
+		return ErrFlushTimeout
+	}
+	return nil
+}
+
+// Close waits for pending events to be sent, then closes the Sentry client.
+func (h *Hook) Close() error {
+	err := h.Flush()
+	h.client.Close()
+	return err
+}
+
+// Event converts a log to a Sentry event (see Hook).
+func Event(l *logs.Log) *sentry.Event {
+	lvl, _ := levelOf(l)
+	event := &sentry.Event{
+		Level:     sentryLevel(lvl),
+		Message:   l.Message,
+		Timestamp: time.Now(),
+		Tags:      map[string]string{},
+		Contexts:  map[string]sentry.Context{},
+		Platform:  "go",
+	}
+	if t, ok := l.Data[logs.DataKeyTimestamp].(time.Time); ok {
+		event.Timestamp = t
+	}
+	if component, ok := l.Data[logs.DataKeyComponent].(string); ok {
+		event.Logger = component
+	}
+	if fp, ok := l.Data[logs.DataKeyFingerprint].(string); ok && fp != "" {
+		event.Fingerprint = []string{fp}
+	}
+
+	// Exception, with the stack trace
+	stack, _ := l.Data[logs.DataKeyStack].([]string)
+	if exception, ok := newException(l, stack); ok {
+		event.Exception = []sentry.Exception{exception}
+	}
+
+	// Tags and trace context
+	for tag, k := range map[string]string{
+		"request_id": logs.DataKeyRequestID,
+		"trace_id":   logs.DataKeyTraceID, // Spans of the logs package, and OpenTelemetry (see the otel contrib)
+		"span_id":    logs.DataKeySpanID,
+	} {
+		if v, ok := l.Data[k].(string); ok && v != "" {
+			event.Tags[tag] = v
+		}
+	}
+	if traceID := event.Tags["trace_id"]; len(traceID) == 32 { // Sentry trace IDs have the size of OpenTelemetry ones
+		event.Contexts["trace"] = sentry.Context{"trace_id": traceID, "span_id": event.Tags["span_id"]}
+	}
+
+	// Data
+	data := sentry.Context{}
+	for k, v := range l.Data {
+		if k == logs.DataKeyStack {
+			continue // Already in the exception
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	if len(data) > 0 {
+		event.Contexts["log"] = data
+	}
+	return event
+}
+
+// newException returns the exception of a log from its panic or error (if any) and stack trace.
+// The log message is used as the type of the exception, which is the title of Sentry issues.
+func newException(l *logs.Log, stack []string) (sentry.Exception, bool) {
+	exception := sentry.Exception{Type: l.Message}
+	if panicValue, ok := l.Data[logs.DataKeyPanic].(string); ok {
+		exception.Type, exception.Value = "panic", panicValue
+		if typ, ok := l.Data[logs.DataKeyPanicType].(string); ok {
+			exception.Type = "panic: " + typ
+		}
+		exception.Mechanism = &sentry.Mechanism{Type: "recover", Handled: boolPtr(true)}
+	} else if msg, ok := l.Data[logs.DataKeyError].(string); ok {
+		exception.Value = msg
+	} else if len(stack) == 0 {
+		return exception, false
+	}
+	if exception.Type == "" {
+		exception.Type = "error"
+	}
+	if len(stack) > 0 {
+		exception.Stacktrace = &sentry.Stacktrace{Frames: parseStack(stack)}
+	}
+	return exception, true
+}
+
+// parseStack converts a stack trace of this package ("function (file:line)" entries, most recent call first)
+// to Sentry frames (most recent call last).
+func parseStack(stack []string) []sentry.Frame {
+	frames := make([]sentry.Frame, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		entry := stack[i]
+		frame := runtime.Frame{Function: entry}
+		if open := strings.LastIndex(entry, " ("); open >= 0 && strings.HasSuffix(entry, ")") {
+			frame.Function = entry[:open]
+			location := entry[open+2 : len(entry)-1]
+			if colon := strings.LastIndex(location, ":"); colon >= 0 {
+				frame.File = location[:colon]
+				frame.Line, _ = strconv.Atoi(location[colon+1:])
+			} else {
+				frame.File = location
+			}
+		}
+		frames = append(frames, sentry.NewFrame(frame))
+	}
+	return frames
+}
+
+// levelOf returns the level of a log.
+func levelOf(l *logs.Log) (logs.LogLevel, bool) {
+	switch v := l.Data[logs.DataKeyLevel].(type) {
+	case logs.LogLevel:
+		return v, true
+	case string:
+		lvl, err := logs.ParseLevel(v)
+		return lvl, err == nil
+	}
+	return logs.LevelUnknown, false
+}
+
+// sentryLevel maps a log level to a Sentry level (custom levels are mapped like the level below them).
+func sentryLevel(lvl logs.LogLevel) sentry.Level {
+	switch {
+	case lvl >= logs.LevelPanic:
+		return sentry.LevelFatal
+	case lvl >= logs.LevelError:
+		return sentry.LevelError
+	case lvl >= logs.LevelWarn:
+		return sentry.LevelWarning
+	case lvl >= logs.LevelInfo:
+		return sentry.LevelInfo
+	}
+	return sentry.LevelDebug
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"time"
+)
+
+// Returns a serializer writing logs as CSV rows (quoted according to RFC 4180) with the given columns, in order.
+// The "message", "level" and "timestamp" columns hold the message, level and creation time of logs,
+// other columns hold the data value with the same key (for ex: "user_id"), or nothing if there is none.
+//
+// Strings are written as is and times in the RFC 3339 format,
+// other values are written like in JSON (for ex: numbers, durations as nanoseconds, or objects).
+// The header row can be written first with CSVHeader, for ex: when creating the file.
+func AsCSV(columns ...string) Serializer {
+	return func(l *Log) []byte {
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = csvValue(l, col)
+		}
+		return csvRow(record)
+	}
+}
+
+// CSVHeader returns the header row of the CSV serializer with the given columns (see AsCSV), without line break.
+func CSVHeader(columns ...string) []byte { return csvRow(columns) }
+
+// csvRow returns a CSV row, without line break.
+func csvRow(record []string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(record)
+	w.Flush()
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// csvValue returns the value of a column for a log.
+func csvValue(l *Log, col string) string {
+	var v any
+	switch col {
+	case "message":
+		return l.Message
+	case "level":
+		v = l.Data[DataKeyLevel]
+	case "timestamp":
+		v = l.Data[DataKeyTimestamp]
+	default:
+		v = l.Data[col]
+	}
+
+	v = stringify(l.formatTime(v), false)
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case LogLevel:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,185 @@
+package logs
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// cycleMarker replaces values that refer back to one of their parents.
+const cycleMarker = "<cycle>"
+
+// withoutCycles returns a copy of the log where self-referential data values
+// are replaced by "<cycle>" at the point where they repeat, so that the log can be marshalled.
+func withoutCycles(l *Log) *Log {
+	out := &Log{Message: l.Message, Data: make(map[string]any, len(l.Data))}
+	for k, v := range l.Data {
+		out.Data[k] = acyclic(reflect.ValueOf(v), map[uintptr]bool{})
+	}
+	return out
+}
+
+// sprint formats a value with fmt, self-referential values are rendered with "<cycle>" where they repeat
+// (fmt would recurse endlessly and overflow the stack).
+func sprint(v any) string {
+	if hasCycle(reflect.ValueOf(v), map[uintptr]bool{}) {
+		v = acyclic(reflect.ValueOf(v), map[uintptr]bool{})
+	}
+	return fmt.Sprint(v)
+}
+
+// hasCycle reports whether a value refers back to one of its parents (through pointers, maps, slices or interfaces).
+// Values implementing error or fmt.Stringer are not inspected since fmt formats them with their method.
+// The visiting set holds the addresses of the parents of the current value.
+func hasCycle(v reflect.Value, visiting map[uintptr]bool) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if v.Type().Implements(errorType) || v.Type().Implements(stringerType) {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return false
+		}
+		addr := v.Pointer()
+		if visiting[addr] {
+			return true
+		}
+		visiting[addr] = true
+		defer delete(visiting, addr)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return hasCycle(v.Elem(), visiting)
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasCycle(iter.Value(), visiting) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasCycle(v.Index(i), visiting) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if hasCycle(v.Field(i), visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// acyclic converts a value to a tree of maps, slices and basic values
+// following the encoding/json conventions (field names, "-" and omitempty tags).
+// The visiting set holds the addresses of the parents of the current value.
+func acyclic(v reflect.Value, visiting map[uintptr]bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return acyclic(v.Elem(), visiting)
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // []byte is encoded as base64 and cannot be cyclic
+		}
+		addr := v.Pointer()
+		if visiting[addr] {
+			return cycleMarker
+		}
+		visiting[addr] = true
+		defer delete(visiting, addr)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return acyclic(v.Elem(), visiting)
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = acyclic(iter.Value(), visiting)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = acyclic(v.Index(i), visiting)
+		}
+		return out
+	case reflect.Struct:
+		out := map[string]any{}
+		addStructFields(out, v, visiting)
+		return out
+	default:
+		if !v.CanInterface() {
+			return nil
+		}
+		return v.Interface()
+	}
+}
+
+// addStructFields adds the exported fields of a struct to a map, embedded structs are flattened.
+func addStructFields(out map[string]any, v reflect.Value, visiting map[uintptr]bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(out, v.Field(i), visiting)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyValue(v.Field(i)) {
+			continue
+		}
+		out[name] = acyclic(v.Field(i), visiting)
+	}
+}
+
+// isEmptyValue reports whether a value is considered empty by the omitempty option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		return false
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// node holds a pointer to itself in tests.
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestSelfReferentialValues(t *testing.T) {
+	self := &node{Name: "a"}
+	self.Next = self
+	m := map[string]any{"k": "v"}
+	m["self"] = m
+
+	jsonSerializers := map[string]Serializer{"json": AsJSON, "pretty": AsPrettyJSON, "ordered": AsOrderedJSON, "otel": AsOTelJSON}
+	textSerializers := map[string]Serializer{
+		"text": AsPlainText, "console": AsConsole, "csv": AsCSV("v"), "cef": AsCEF(SIEMOptions{}), "leef": AsLEEF(SIEMOptions{}),
+		"gelf": AsGELF, "journal": AsJournal,
+	}
+	for _, v := range []any{self, m} {
+		l := NewLog("msg", WithData("v", v))
+		for name, s := range jsonSerializers {
+			b := s(l)
+			if !json.Valid(b) || !strings.Contains(string(b), "cycle") { // "<" and ">" may be escaped
+				t.Errorf("%s: %s, want valid JSON with %q", name, b, cycleMarker)
+			}
+		}
+		for name, s := range textSerializers {
+			if b := s(l); !strings.Contains(string(b), "cycle") {
+				t.Errorf("%s: %s, want %q", name, b, cycleMarker)
+			}
+		}
+	}
+}
+
+func TestTextValuesWithoutCyclesAreUnchanged(t *testing.T) {
+	shared := &node{Name: "shared"}
+	l := NewLog("msg", WithData("v", []*node{shared, shared}), WithData("m", map[string]int{"a": 1}))
+	if got, want := string(AsPlainText(l)), "msg, m: map[a:1], v: ["; !strings.HasPrefix(got, want) || strings.Contains(got, cycleMarker) {
+		t.Fatalf("got %q, want the fmt representation", got)
+	}
+}
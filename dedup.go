@@ -0,0 +1,75 @@
+package logs
+
+import "time"
+
+// DataKeyRepeatCount holds the number of times a log was repeated in deduplication summaries (see DefaultLogger.DedupWindow).
+const DataKeyRepeatCount = dataKeyPrefix + "repeat_count"
+
+// deduplicator holds the state of the deduplication of a logger.
+type deduplicator struct {
+	seen      map[string]*repeatedLog
+	nextSweep time.Time // Earliest end of the windows in seen
+}
+
+// repeatedLog holds the repeats of a log during a window.
+type repeatedLog struct {
+	windowEnd time.Time
+	count     int              // Number of repeats that were dropped
+	last      *Log             // Last repeat
+	write     func(*Log) error // Writes the summary with the logger func of the repeats
+}
+
+// allow reports whether a log should be written: repeats of a log written less than window ago are dropped.
+// Write is used to write the summary of the repeats once the window is over (see sweep).
+func (d *deduplicator) allow(window time.Duration, l *Log, write func(*Log) error) bool {
+	now := time.Now()
+	key := identityKey(l)
+	if r, ok := d.seen[key]; ok && now.Before(r.windowEnd) {
+		r.count++
+		r.last = l.clone() // The caller may reuse the log once it is written
+		return false
+	}
+	if d.seen == nil {
+		d.seen = map[string]*repeatedLog{}
+	}
+	r := &repeatedLog{windowEnd: now.Add(window), write: write}
+	d.seen[key] = r
+	if d.nextSweep.IsZero() || r.windowEnd.Before(d.nextSweep) {
+		d.nextSweep = r.windowEnd
+	}
+	return true
+}
+
+// sweep forgets the logs whose window is over (or all logs if all is true),
+// writing a summary for those that were repeated: a copy of the last repeat with the number of repeats.
+func (d *deduplicator) sweep(all bool) {
+	now := time.Now()
+	if !all && (d.nextSweep.IsZero() || now.Before(d.nextSweep)) {
+		return
+	}
+	d.nextSweep = time.Time{}
+	for key, r := range d.seen {
+		if !all && now.Before(r.windowEnd) {
+			if d.nextSweep.IsZero() || r.windowEnd.Before(d.nextSweep) {
+				d.nextSweep = r.windowEnd
+			}
+			continue
+		}
+		delete(d.seen, key)
+		if r.count == 0 {
+			continue
+		}
+		summary := r.last
+		summary.Data[DataKeyRepeatCount] = r.count
+		r.write(summary)
+	}
+}
+
+// identityKey returns the key identifying identical logs (same level and message).
+func identityKey(l *Log) string {
+	key := l.Message
+	if lvl, ok := l.Data[DataKeyLevel].(string); ok {
+		key = lvl + " " + key
+	}
+	return key
+}
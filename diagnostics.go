@@ -0,0 +1,154 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Data keys of the logs written by Diagnostics.
+const (
+	DataKeyOutput     = dataKeyPrefix + "output"      // Name of the failing output
+	DataKeyFailures   = dataKeyPrefix + "failures"    // Number of consecutive failures
+	DataKeyErrorCount = dataKeyPrefix + "error_count" // Number of failures since the previous report
+)
+
+// Diagnostics reports failing outputs with its own logs written to a fallback writer,
+// so that logs lost because of a broken output (for ex: a full disk or an unreachable server) don't go unnoticed.
+// Use it as the Diagnostics of a DefaultLogger to report its writers and sinks,
+// and use its OnError method as the error handler of background writers (for ex: LokiWriter).
+// The zero value is ready to use, fields must be set before the first report.
+//
+// Once an output has failed Threshold times in a row, a WARN log is written with the name of the output,
+// the number of consecutive failures, the number of failures and of dropped logs since the previous report
+// (under DataKeyErrorCount and DataKeyDropped) and the last error (under DataKeyError).
+// While the output keeps failing, it is reported again at most once per Interval.
+// When a reported output of a DefaultLogger works again, an INFO log is written.
+type Diagnostics struct {
+	Writer     io.Writer     // For ex: a file on another disk (os.Stderr by default)
+	Serializer Serializer    // For ex: AsJSON (AsPlainText by default)
+	Threshold  int           // For ex: 1 to report the first failure (3 consecutive failures by default)
+	Interval   time.Duration // For ex: 1m between reports of an output that keeps failing (10s by default)
+
+	mu      sync.Mutex
+	outputs map[string]*outputHealth // By output name
+}
+
+// outputHealth holds the failures of an output.
+type outputHealth struct {
+	failures int       // Consecutive failures
+	errors   int       // Failures since the previous report
+	dropped  int       // Dropped logs since the previous report
+	lastErr  error     // Last error
+	reported time.Time // Time of the previous report (zero if the output has not been reported as failing)
+}
+
+// Failure records a failure of the named output, dropped is the number of logs lost because of it.
+func (d *Diagnostics) Failure(output string, err error, dropped int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.outputs == nil {
+		d.outputs = map[string]*outputHealth{}
+	}
+	h, ok := d.outputs[output]
+	if !ok {
+		h = &outputHealth{}
+		d.outputs[output] = h
+	}
+	h.failures++
+	h.errors++
+	h.dropped += dropped
+	h.lastErr = err
+
+	threshold := d.Threshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+	interval := d.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	now := time.Now()
+	if h.failures < threshold || (!h.reported.IsZero() && now.Sub(h.reported) < interval) {
+		return
+	}
+	d.write(NewLog("logs: output is failing",
+		WithTimestamp(),
+		WithLevel(LevelWarn.String()),
+		WithData(DataKeyOutput, output),
+		WithData(DataKeyFailures, h.failures),
+		WithData(DataKeyErrorCount, h.errors),
+		WithData(DataKeyDropped, h.dropped),
+		WithData(DataKeyError, h.lastErr.Error()),
+	))
+	h.errors, h.dropped, h.reported = 0, 0, now
+}
+
+// Success records a successful write of the named output, reporting its recovery if it was reported as failing.
+func (d *Diagnostics) Success(output string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.outputs[output]
+	if !ok {
+		return
+	}
+	delete(d.outputs, output)
+	if h.reported.IsZero() {
+		return
+	}
+	d.write(NewLog("logs: output recovered",
+		WithTimestamp(),
+		WithLevel(LevelInfo.String()),
+		WithData(DataKeyOutput, output),
+		WithData(DataKeyFailures, h.failures),
+		WithData(DataKeyErrorCount, h.errors),
+		WithData(DataKeyDropped, h.dropped),
+	))
+}
+
+// OnError returns an error handler recording failures of the named output,
+// for ex: lokiWriter.OnError = diagnostics.OnError("loki").
+// Background writers don't report successful writes: the output is reported as long as it keeps failing.
+func (d *Diagnostics) OnError(output string) func(error) {
+	return func(err error) { d.Failure(output, err, 0) }
+}
+
+// write writes a diagnostics log to the fallback writer, errors are ignored since there is nowhere left to report them.
+// It must be called while holding the lock.
+func (d *Diagnostics) write(l *Log) {
+	serializer := d.Serializer
+	if serializer == nil {
+		serializer = AsPlainText
+	}
+	b, err := serialize(serializer, l)
+	if err != nil {
+		return
+	}
+	w := d.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+	w.Write(append(b, '\n'))
+}
+
+// observe records the result of a write to the named output (if diagnostics are enabled).
+func (d *Diagnostics) observe(output string, err error) {
+	if d == nil {
+		return
+	}
+	if err != nil {
+		d.Failure(output, err, 1)
+		return
+	}
+	d.Success(output)
+}
+
+// outputName returns the name of an output reported by diagnostics, for ex: "writers[1] (*os.File /var/log/app.log)".
+func outputName(kind string, i int, w io.Writer) string {
+	if named, ok := w.(interface{ Name() string }); ok {
+		return fmt.Sprintf("%s[%d] (%T %s)", kind, i, w, named.Name())
+	}
+	return fmt.Sprintf("%s[%d] (%T)", kind, i, w)
+}
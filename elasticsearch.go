@@ -0,0 +1,374 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ElasticsearchWriter indexes logs in Elasticsearch (or OpenSearch) with the bulk API.
+// It implements LogWriter: use it as a writer of a DefaultLogger with a JSON serializer
+// (for ex: AsOrderedJSON, so that data keys are top-level fields of the documents).
+// Fields must be set before the first write.
+//
+// The index of each log is named after its creation time (see Index), for ex: one index per day.
+// Logs are indexed by batches from a background goroutine: requests failing with a network error,
+// rate limiting (429) or a server error are retried with an exponential backoff, and so are the documents
+// rejected with a 429 status. Other rejected documents (for ex: with a mapping conflict) are appended
+// to the dead-letter file (if set) along with the error, so that they can be inspected and indexed again.
+// Flush or Close must be called to make sure all logs are indexed (DefaultLogger.Flush and Close do it).
+// When QueueSize batches are already waiting to be indexed, writes block until Elasticsearch catches up.
+type ElasticsearchWriter struct {
+	Index          string        // For ex: "app-logs-{2006.01}" (the "{...}" parts are time layouts, "logs-{2006.01.02}" by default)
+	Client         *http.Client  // For ex: a client with a custom transport (a client with a 30s timeout by default)
+	Header         http.Header   // For ex: an "Authorization" header added to all requests
+	BatchSize      int           // For ex: 1 MB of documents per request (5 MB by default)
+	FlushInterval  time.Duration // For ex: 5s to index incomplete batches periodically (1s by default)
+	QueueSize      int           // For ex: 16 batches waiting to be indexed before writes block (4 by default)
+	MaxRetries     int           // For ex: 10 attempts after the first one (5 by default, a negative number disables retries)
+	DeadLetterPath string        // For ex: "/var/log/app/rejected.ndjson" (rejected documents are dropped by default)
+	OnError        func(error)   // For ex: write a fallback line to stderr (errors are ignored by default)
+
+	url       string
+	mu        sync.Mutex
+	batch     []esDocument
+	size      int // Size of the documents of the current batch
+	queue     chan esQueued
+	inflight  sync.WaitGroup // Writes queueing a batch
+	startOnce sync.Once
+	stopped   chan struct{}
+	closed    bool
+}
+
+// esDocument is a log to index.
+type esDocument struct {
+	index string
+	doc   []byte
+}
+
+// esQueued is a batch waiting to be indexed, done (if not nil) receives the result once it is indexed.
+type esQueued struct {
+	batch []esDocument
+	done  chan error
+}
+
+// NewElasticsearchWriter returns a writer indexing logs in the cluster at the given URL (for ex: "http://localhost:9200").
+func NewElasticsearchWriter(url string) *ElasticsearchWriter {
+	return &ElasticsearchWriter{url: strings.TrimSuffix(url, "/"), stopped: make(chan struct{})}
+}
+
+// WriteLog adds the log to the current batch (b is the document), queueing the batch if it is full.
+func (ew *ElasticsearchWriter) WriteLog(l *Log, b []byte) error {
+	ew.startOnce.Do(ew.start)
+
+	ts := time.Now()
+	if t, ok := l.Data[DataKeyTimestamp].(time.Time); ok {
+		ts = t
+	}
+	doc := esDocument{index: ew.indexName(ts), doc: bytes.TrimSpace(b)}
+
+	ew.mu.Lock()
+	if ew.closed {
+		ew.mu.Unlock()
+		return os.ErrClosed
+	}
+	ew.batch = append(ew.batch, doc)
+	ew.size += len(doc.doc)
+	var full []esDocument
+	if ew.size >= ew.batchSize() {
+		full, ew.batch, ew.size = ew.batch, nil, 0
+		ew.inflight.Add(1)
+	}
+	ew.mu.Unlock()
+
+	if full != nil {
+		defer ew.inflight.Done()
+		ew.queue <- esQueued{batch: full}
+	}
+	return nil
+}
+
+// Write decodes a log serialized as JSON (see NewReader) and adds it to the current batch.
+func (ew *ElasticsearchWriter) Write(b []byte) (int, error) {
+	l, err := NewReader(bytes.NewReader(b)).Next()
+	if err != nil {
+		return 0, fmt.Errorf("decode log: %w", err)
+	}
+	if err := ew.WriteLog(l, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush indexes the current batch and waits until all queued batches are indexed.
+func (ew *ElasticsearchWriter) Flush() error {
+	ew.startOnce.Do(ew.start)
+
+	ew.mu.Lock()
+	if ew.closed {
+		ew.mu.Unlock()
+		return nil
+	}
+	batch := ew.batch
+	ew.batch, ew.size = nil, 0
+	ew.inflight.Add(1)
+	ew.mu.Unlock()
+
+	defer ew.inflight.Done()
+	done := make(chan error, 1)
+	ew.queue <- esQueued{batch: batch, done: done}
+	return <-done
+}
+
+// Close indexes the current batch, waits until all queued batches are indexed and stops the background goroutine.
+func (ew *ElasticsearchWriter) Close() error {
+	ew.startOnce.Do(ew.start)
+
+	ew.mu.Lock()
+	if ew.closed {
+		ew.mu.Unlock()
+		return nil
+	}
+	ew.closed = true
+	batch := ew.batch
+	ew.batch, ew.size = nil, 0
+	ew.mu.Unlock()
+
+	ew.inflight.Wait()
+	done := make(chan error, 1)
+	ew.queue <- esQueued{batch: batch, done: done}
+	close(ew.queue)
+	err := <-done
+	<-ew.stopped
+	return err
+}
+
+// start creates the queue and starts the background goroutine indexing batches.
+func (ew *ElasticsearchWriter) start() {
+	size := ew.QueueSize
+	if size <= 0 {
+		size = 4
+	}
+	interval := ew.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ew.queue = make(chan esQueued, size)
+
+	go func() {
+		defer close(ew.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case queued, ok := <-ew.queue:
+				if !ok {
+					return
+				}
+				err := ew.send(queued.batch)
+				if queued.done != nil {
+					queued.done <- err
+				} else if err != nil {
+					ew.report(err)
+				}
+			case <-ticker.C:
+				ew.mu.Lock()
+				batch := ew.batch
+				ew.batch, ew.size = nil, 0
+				ew.mu.Unlock()
+				if err := ew.send(batch); err != nil {
+					ew.report(err)
+				}
+			}
+		}
+	}()
+}
+
+// send indexes a batch, retrying the request and the documents rejected with a 429 status with an exponential backoff.
+// Documents rejected for other reasons are written to the dead-letter file.
+func (ew *ElasticsearchWriter) send(batch []esDocument) error {
+	retries := ew.MaxRetries
+	if retries == 0 {
+		retries = 5
+	}
+	backoff := 500 * time.Millisecond
+	for attempt := 0; len(batch) > 0; attempt++ {
+		retry, rejected, err := ew.bulk(batch)
+		if len(rejected) > 0 {
+			if dlErr := ew.deadLetter(rejected); dlErr != nil {
+				ew.report(dlErr)
+			}
+		}
+		if len(retry) == 0 {
+			return err
+		}
+		if attempt >= retries {
+			if err == nil {
+				err = fmt.Errorf("index logs: %d documents rejected with status 429 after %d attempts", len(retry), attempt+1)
+			}
+			return err
+		}
+		batch = retry
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+	return nil
+}
+
+// esRejected is a document rejected by Elasticsearch, as written to the dead-letter file.
+type esRejected struct {
+	Time     time.Time       `json:"time"`
+	Index    string          `json:"index"`
+	Status   int             `json:"status"`
+	Error    json.RawMessage `json:"error"`
+	Document json.RawMessage `json:"document"`
+}
+
+// bulk sends a bulk request, it returns the documents to retry (all of them if the request failed temporarily)
+// and the rejected documents. The error is only returned if the request failed.
+func (ew *ElasticsearchWriter) bulk(batch []esDocument) (retry []esDocument, rejected []esRejected, err error) {
+	var body bytes.Buffer
+	for _, doc := range batch {
+		action, _ := json.Marshal(map[string]any{"create": map[string]string{"_index": doc.index}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.doc)
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequest(http.MethodPost, ew.url+"/_bulk", &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, values := range ew.Header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	client := ew.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return batch, nil, fmt.Errorf("index logs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		io.Copy(io.Discard, resp.Body)
+		return batch, nil, fmt.Errorf("index logs: unexpected status %s", resp.Status)
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("index logs: unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("index logs: decode response: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	if len(result.Items) != len(batch) {
+		return nil, nil, errors.New("index logs: unexpected number of items in response")
+	}
+	for i, item := range result.Items {
+		for _, res := range item {
+			switch {
+			case res.Status == http.StatusTooManyRequests:
+				retry = append(retry, batch[i])
+			case res.Status < 200 || res.Status > 299:
+				rejected = append(rejected, esRejected{
+					Time:     time.Now(),
+					Index:    batch[i].index,
+					Status:   res.Status,
+					Error:    res.Error,
+					Document: json.RawMessage(batch[i].doc),
+				})
+			}
+		}
+	}
+	return retry, rejected, nil
+}
+
+// deadLetter appends rejected documents to the dead-letter file (one JSON object per line).
+func (ew *ElasticsearchWriter) deadLetter(rejected []esRejected) error {
+	if ew.DeadLetterPath == "" {
+		return fmt.Errorf("index logs: %d documents rejected", len(rejected))
+	}
+	var buf bytes.Buffer
+	for _, r := range rejected {
+		if !json.Valid(r.Document) {
+			b, _ := json.Marshal(string(r.Document))
+			r.Document = b
+		}
+		if len(r.Error) == 0 {
+			r.Error = json.RawMessage("null")
+		}
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	f, err := os.OpenFile(ew.DeadLetterPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// indexName returns the name of the index for a log created at the given time,
+// formatting the "{...}" parts of the index template with the time (in UTC).
+func (ew *ElasticsearchWriter) indexName(t time.Time) string {
+	tmpl := ew.Index
+	if tmpl == "" {
+		tmpl = "logs-{2006.01.02}"
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		end := strings.IndexByte(tmpl, '}')
+		if start < 0 || end < start {
+			sb.WriteString(tmpl)
+			return sb.String()
+		}
+		sb.WriteString(tmpl[:start])
+		sb.WriteString(t.UTC().Format(tmpl[start+1 : end]))
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// report passes an error of the background goroutine to OnError (if set).
+func (ew *ElasticsearchWriter) report(err error) {
+	if ew.OnError != nil {
+		ew.OnError(err)
+	}
+}
+
+// batchSize returns the configured batch size or the default one.
+func (ew *ElasticsearchWriter) batchSize() int {
+	if ew.BatchSize <= 0 {
+		return 5 << 20
+	}
+	return ew.BatchSize
+}
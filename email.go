@@ -0,0 +1,272 @@
+package logs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmailDigest batches severe logs and sends them as digest emails over SMTP at a regular interval,
+// for ex: for small deployments without an alerting stack.
+// It is meant to be used as a destination of a Router (with its Log method), fields must be set before the first log.
+//
+// At most MaxLogs logs are included in a digest, the number of other logs is mentioned at the end.
+// No email is sent for an interval without logs.
+// Digests are sent without blocking Log, a digest that fails to be sent is merged into the next one
+// (until the digest is closed).
+type EmailDigest struct {
+	Subject    string        // For ex: "[billing-api] errors" (the number of logs is appended, "Log digest" by default)
+	MinLevel   LogLevel      // For ex: LevelPanic (LevelError by default), logs without a level are ignored
+	Interval   time.Duration // For ex: 1h between digests (15m by default)
+	MaxLogs    int           // For ex: 500 logs per digest at most (100 by default)
+	Serializer Serializer    // For ex: AsPrettyJSON (AsPlainText by default)
+	Timeout    time.Duration // For ex: 30s to connect to the server and send a digest (1m by default)
+	OnError    func(error)   // For ex: write a fallback line to stderr (errors are ignored by default)
+
+	addr      string
+	auth      smtp.Auth
+	from      string
+	to        []string
+	mu        sync.Mutex
+	sending   sync.Mutex // Held while sending a digest, so that digests are sent one at a time
+	pending   []string   // Serialized logs of the current digest
+	omitted   int        // Logs of the current digest beyond MaxLogs
+	startOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+	closed    bool
+}
+
+// NewEmailDigest returns a digest sending emails from the given address to the given recipients
+// with the SMTP server at addr (for ex: "smtp.example.com:587"), auth is optional (for ex: smtp.PlainAuth).
+// STARTTLS is used when the server supports it (see smtp.SendMail).
+func NewEmailDigest(addr string, auth smtp.Auth, from string, to ...string) *EmailDigest {
+	return &EmailDigest{
+		addr:    addr,
+		auth:    auth,
+		from:    from,
+		to:      to,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Log adds the log to the current digest if its level is at or above the minimum level.
+func (ed *EmailDigest) Log(l *Log) error {
+	minLevel := ed.MinLevel
+	if minLevel == LevelUnknown {
+		minLevel = LevelError
+	}
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); !ok || lvl < minLevel {
+		return nil
+	}
+	ed.startOnce.Do(ed.start)
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	if ed.closed {
+		return ErrLoggerClosed
+	}
+	if len(ed.pending) >= ed.maxLogs() {
+		ed.omitted++
+		return nil
+	}
+	serializer := ed.Serializer
+	if serializer == nil {
+		serializer = AsPlainText
+	}
+	b, err := serialize(serializer, l)
+	if err != nil {
+		return err
+	}
+	ed.pending = append(ed.pending, string(b))
+	return nil
+}
+
+// Flush sends the current digest now (if it holds logs).
+func (ed *EmailDigest) Flush() error { return ed.send() }
+
+// Close sends the current digest (if it holds logs) and stops sending digests,
+// logs are dropped afterwards (and ErrLoggerClosed is returned).
+func (ed *EmailDigest) Close() error {
+	ed.mu.Lock()
+	if ed.closed {
+		ed.mu.Unlock()
+		return nil
+	}
+	ed.closed = true
+	ed.mu.Unlock()
+
+	close(ed.done)
+	ed.startOnce.Do(func() { close(ed.stopped) }) // Not started
+	<-ed.stopped
+	return ed.send()
+}
+
+// start starts the background goroutine sending digests.
+func (ed *EmailDigest) start() {
+	interval := ed.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	go func() {
+		defer close(ed.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ed.done:
+				return
+			case <-ticker.C:
+				if err := ed.send(); err != nil && ed.OnError != nil {
+					ed.OnError(err)
+				}
+			}
+		}
+	}()
+}
+
+// maxLogs returns the maximum number of logs included in a digest.
+func (ed *EmailDigest) maxLogs() int {
+	if ed.MaxLogs <= 0 {
+		return 100
+	}
+	return ed.MaxLogs
+}
+
+// send sends the current digest (if it holds logs) and starts a new one, without holding the lock.
+// If the digest cannot be sent, its logs are put back before the logs added since (unless the digest is closed).
+func (ed *EmailDigest) send() error {
+	ed.sending.Lock()
+	defer ed.sending.Unlock()
+
+	ed.mu.Lock()
+	pending, omitted := ed.pending, ed.omitted
+	ed.pending, ed.omitted = nil, 0
+	ed.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	msg, err := ed.message(pending, omitted)
+	if err == nil {
+		err = ed.sendMail(msg)
+	}
+	if err != nil {
+		ed.requeue(pending, omitted)
+		return fmt.Errorf("send log digest: %w", err)
+	}
+	return nil
+}
+
+// requeue puts back the logs of a digest that could not be sent before the logs added since,
+// logs beyond MaxLogs are counted as omitted.
+func (ed *EmailDigest) requeue(pending []string, omitted int) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	if ed.closed {
+		return
+	}
+	pending = append(pending, ed.pending...)
+	if max := ed.maxLogs(); len(pending) > max {
+		omitted += len(pending) - max
+		pending = pending[:max]
+	}
+	ed.pending, ed.omitted = pending, ed.omitted+omitted
+}
+
+// sendMail sends an email like smtp.SendMail, within the timeout of the digest.
+// STARTTLS is used when the server supports it.
+func (ed *EmailDigest) sendMail(msg []byte) error {
+	timeout := ed.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	conn, err := net.DialTimeout("tcp", ed.addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(ed.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if ok, _ := c.Extension("AUTH"); ok && ed.auth != nil {
+		if err := c.Auth(ed.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(ed.from); err != nil {
+		return err
+	}
+	for _, to := range ed.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message returns the email of a digest, with a quoted-printable body.
+func (ed *EmailDigest) message(pending []string, omitted int) ([]byte, error) {
+	count := len(pending) + omitted
+	subject := ed.Subject
+	if subject == "" {
+		subject = "Log digest"
+	}
+	subject = fmt.Sprintf("%s (%d logs)", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject), count)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", ed.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(ed.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	for _, s := range pending {
+		if _, err := fmt.Fprintf(qp, "%s\r\n\r\n", s); err != nil {
+			return nil, err
+		}
+	}
+	if omitted > 0 {
+		if _, err := fmt.Fprintf(qp, "... and %d more logs.\r\n", omitted); err != nil {
+			return nil, err
+		}
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,57 @@
+package logs
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailDigestHungServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept() // Never greets the client
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ed := NewEmailDigest(ln.Addr().String(), nil, "from@example.com", "to@example.com")
+	ed.Timeout = 200 * time.Millisecond
+	ed.Log(NewLog("first", WithLevel(LevelError.String())))
+
+	flushed := make(chan error)
+	go func() { flushed <- ed.Flush() }()
+	time.Sleep(50 * time.Millisecond) // Let Flush connect to the server
+
+	logged := make(chan struct{})
+	go func() { ed.Log(NewLog("second", WithLevel(LevelError.String()))); close(logged) }()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked while a digest was being sent")
+	}
+
+	select {
+	case err := <-flushed:
+		if err == nil {
+			t.Fatal("Flush succeeded with a hung server")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush didn't time out")
+	}
+
+	ed.mu.Lock()
+	pending := ed.pending
+	ed.mu.Unlock()
+	if len(pending) != 2 || !strings.Contains(pending[0], "first") || !strings.Contains(pending[1], "second") {
+		t.Fatalf("%d pending logs, want the digest that failed to be sent followed by the new log", len(pending))
+	}
+}
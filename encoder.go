@@ -0,0 +1,290 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// Encoder serializes logs as JSON, its options are shared by all the logs it encodes.
+// JSON object keys are always sorted, so the output is deterministic.
+// In flat mode, the timestamp, level and message come first (whatever their key), see AsOrderedJSON.
+//
+// Data values implementing error are encoded as their error message
+// (unless they implement json.Marshaler), instead of the empty object
+// that encoding/json produces for most error types, also when held in slices and maps.
+type Encoder struct {
+	Indent              string   // For ex: "\t" (leave empty for single-line JSON)
+	DisableHTMLEscaping bool     // For ex: true to keep "<", ">" and "&" as is in URLs
+	RedactKeys          []string // For ex: "password" to mask the value of this data key
+	UseStringer         bool     // For ex: true to encode data values implementing fmt.Stringer as their string
+	Flat                bool     // For ex: true to encode the timestamp, level, message and sorted data keys (in this order) in a single object
+}
+
+// Used by the AsJSON and AsPrettyJSON serializers.
+var (
+	defaultEncoder = &Encoder{}
+	prettyEncoder  = &Encoder{Indent: "\t"}
+	orderedEncoder = &Encoder{Flat: true}
+)
+
+// Stands in for the value of redacted data keys.
+const redactedValue = "[REDACTED]"
+
+// Encode returns the JSON representation of a log.
+// Self-referential data values are rendered as "<cycle>" where they repeat.
+// This method will panic if the JSON encoding of the log returns an error.
+func (e *Encoder) Encode(l *Log) []byte {
+	l = e.prepare(l)
+	b, err := e.marshalLog(l)
+	if err != nil {
+		b, err = e.marshalLog(withoutCycles(l))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// EncodeTo writes the JSON representation of a log directly to w (without trailing line break),
+// this avoids copying the encoded log to add a prefix and suffix before writing it.
+// Self-referential data values are rendered as "<cycle>" where they repeat.
+func (e *Encoder) EncodeTo(l *Log, w io.Writer) error {
+	l = e.prepare(l)
+	if e.Flat {
+		b, err := e.marshalLog(l)
+		if err != nil {
+			b, err = e.marshalLog(withoutCycles(l))
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	enc := e.newJSONEncoder(&newlineTrimmer{w: w})
+	err := enc.Encode(l)
+	if err != nil {
+		err = enc.Encode(withoutCycles(l)) // nothing is written when encoding fails
+	}
+	return err
+}
+
+// Serializer returns a serializer using the encoder.
+func (e *Encoder) Serializer() Serializer { return e.Encode }
+
+// marshalLog encodes a log with the encoder options.
+func (e *Encoder) marshalLog(l *Log) ([]byte, error) {
+	if e.Flat {
+		return e.marshalFlat(l)
+	}
+	return e.marshal(l)
+}
+
+// marshal encodes a value with the encoder options.
+func (e *Encoder) marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := e.newJSONEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil // remove newline added by the encoder
+}
+
+// marshalFlat encodes the timestamp, level, message and remaining data keys (sorted) of a log in a single object.
+// A data key named "message" is encoded as "data.message" so that it doesn't collide with the message.
+func (e *Encoder) marshalFlat(l *Log) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	writeField := func(k string, v any) error {
+		key, err := e.marshal(k)
+		if err != nil {
+			return err
+		}
+		value, err := e.marshal(v)
+		if err != nil {
+			return err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+		return nil
+	}
+
+	keys := sortedDataKeys(l)
+	for len(keys) > 0 && (keys[0] == DataKeyTimestamp || keys[0] == DataKeyLevel) {
+		if err := writeField(keys[0], l.Data[keys[0]]); err != nil {
+			return nil, err
+		}
+		keys = keys[1:]
+	}
+	if err := writeField("message", l.Message); err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		name := k
+		if k == "message" {
+			name = "data.message"
+		}
+		if err := writeField(name, l.Data[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	if e.Indent == "" {
+		return buf.Bytes(), nil
+	}
+	indented := &bytes.Buffer{}
+	err := json.Indent(indented, buf.Bytes(), "", e.Indent)
+	return indented.Bytes(), err
+}
+
+// sortedDataKeys returns the data keys of a log: the timestamp and level first (if present),
+// followed by the other keys sorted alphabetically.
+func sortedDataKeys(l *Log) []string {
+	keys := make([]string, 0, len(l.Data))
+	for k := range l.Data {
+		if k != DataKeyTimestamp && k != DataKeyLevel {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range []string{DataKeyLevel, DataKeyTimestamp} {
+		if _, ok := l.Data[k]; ok {
+			keys = append([]string{k}, keys...)
+		}
+	}
+	return keys
+}
+
+// newJSONEncoder returns a JSON encoder writing to w configured with the encoder options.
+func (e *Encoder) newJSONEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", e.Indent)
+	enc.SetEscapeHTML(!e.DisableHTMLEscaping)
+	return enc
+}
+
+// prepare returns a copy of the log where the values of the redacted keys are masked,
+// errors (and stringers if enabled) are replaced by their string and times are formatted (see DefaultLogger.TimeFormat).
+// The original log is left untouched.
+func (e *Encoder) prepare(l *Log) *Log {
+	out := &Log{Message: l.Message, Data: make(map[string]any, len(l.Data))}
+	for k, v := range l.Data {
+		out.Data[k] = stringify(l.formatTime(v), e.UseStringer)
+	}
+	for _, k := range e.RedactKeys {
+		if _, ok := out.Data[k]; ok {
+			out.Data[k] = redactedValue
+		}
+	}
+	return out
+}
+
+// stringify returns the message of errors (and the string of stringers if enabled)
+// that don't define their own JSON representation, other values are returned as is.
+// Errors (and stringers) held in slices, arrays and maps with string keys are converted too (the containers are copied then).
+func stringify(v any, useStringer bool) any {
+	out, _ := convertStringers(v, useStringer, 0)
+	return out
+}
+
+// Nesting depth after which errors and stringers are not converted anymore (in case of a cycle).
+const maxStringifyDepth = 10
+
+// convertStringers converts a value like stringify, it reports whether the value was converted.
+func convertStringers(v any, useStringer bool, depth int) (out any, converted bool) {
+	if _, ok := v.(json.Marshaler); ok || v == nil || depth >= maxStringifyDepth {
+		return v, false
+	}
+	if err, ok := v.(error); ok {
+		return err.Error(), true
+	}
+	if s, ok := v.(fmt.Stringer); ok && useStringer {
+		return s.String(), true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !mayHoldStringer(rv.Type().Elem(), useStringer) || (rv.Kind() == reflect.Slice && rv.IsNil()) {
+			return v, false
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			var ok bool
+			items[i], ok = convertStringers(rv.Index(i).Interface(), useStringer, depth+1)
+			converted = converted || ok
+		}
+		if converted {
+			return items, true
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || !mayHoldStringer(rv.Type().Elem(), useStringer) || rv.IsNil() {
+			return v, false
+		}
+		entries := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			var ok bool
+			entries[iter.Key().String()], ok = convertStringers(iter.Value().Interface(), useStringer, depth+1)
+			converted = converted || ok
+		}
+		if converted {
+			return entries, true
+		}
+	}
+	return v, false
+}
+
+// mayHoldStringer reports whether values of the given type can be or hold errors (or stringers if enabled)
+// converted by stringify.
+func mayHoldStringer(t reflect.Type, useStringer bool) bool {
+	switch {
+	case t.Implements(jsonMarshalerType):
+		return false
+	case t.Kind() == reflect.Interface || t.Implements(errorType) || (useStringer && t.Implements(stringerType)):
+		return true
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map:
+		return mayHoldStringer(t.Elem(), useStringer)
+	}
+	return false
+}
+
+// StreamSerializer writes a serialized log directly to a writer.
+// DefaultLogger streams logs straight to its writers (see DefaultLogger.StreamSerializer),
+// so that large logs are never held in memory once serialized.
+type StreamSerializer func(*Log, io.Writer) error
+
+// SerializeTo writes the single-line-JSON representation of a log directly to w.
+func SerializeTo(l *Log, w io.Writer) error { return defaultEncoder.EncodeTo(l, w) }
+
+// newlineTrimmer drops the line break written last, like the one added by json.Encoder.
+type newlineTrimmer struct {
+	w    io.Writer
+	held bool // Whether a line break is being held back
+}
+
+func (nt *newlineTrimmer) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	out := b
+	if nt.held {
+		out = append([]byte("\n"), b...)
+	}
+	nt.held = out[len(out)-1] == '\n'
+	if nt.held {
+		out = out[:len(out)-1]
+	}
+	if _, err := nt.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
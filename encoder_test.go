@@ -0,0 +1,40 @@
+package logs
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// color implements fmt.Stringer.
+type color int
+
+func (c color) String() string { return [...]string{"red", "green"}[c] }
+
+func TestEncoderErrorsAndStringers(t *testing.T) {
+	l := NewLog("msg",
+		WithData("err", io.EOF),
+		WithData("errs", []error{io.EOF, errors.New("boom")}),
+		WithData("nested", map[string]any{"err": io.EOF, "list": []any{1, io.EOF}}),
+		WithData("color", color(1)),
+		WithData("colors", []color{0, 1}),
+	)
+	tests := []struct {
+		encoder *Encoder
+		want    string
+	}{
+		{
+			&Encoder{},
+			`{"message":"msg","data":{"color":1,"colors":[0,1],"err":"EOF","errs":["EOF","boom"],"nested":{"err":"EOF","list":[1,"EOF"]}}}`,
+		},
+		{
+			&Encoder{UseStringer: true},
+			`{"message":"msg","data":{"color":"green","colors":["red","green"],"err":"EOF","errs":["EOF","boom"],"nested":{"err":"EOF","list":[1,"EOF"]}}}`,
+		},
+	}
+	for _, tt := range tests {
+		if got := string(tt.encoder.Encode(l)); got != tt.want {
+			t.Errorf("UseStringer=%v:\ngot  %s\nwant %s", tt.encoder.UseStringer, got, tt.want)
+		}
+	}
+}
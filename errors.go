@@ -0,0 +1,115 @@
+package logs
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	DataKeyError      = dataKeyPrefix + "error"
+	DataKeyErrorChain = dataKeyPrefix + "error_chain"
+	DataKeyStack      = dataKeyPrefix + "stack"
+)
+
+// WithError adds an error message to the log, along with the messages of the errors it wraps (if any).
+// Nothing is added if the error is nil.
+func WithError(err error) LogOption {
+	return func(l *Log) {
+		if err == nil {
+			return
+		}
+		l.Data[DataKeyError] = err.Error()
+		if chain := errorChain(err); len(chain) > 0 {
+			l.Data[DataKeyErrorChain] = chain
+		}
+	}
+}
+
+// WithErrorStack is like WithError but also adds the stack trace of the goroutine where the option was created.
+func WithErrorStack(err error) LogOption {
+	stack := callerStack(1)
+	return func(l *Log) {
+		if err == nil {
+			return
+		}
+		WithError(err)(l)
+		l.Data[DataKeyStack] = stack
+	}
+}
+
+// WithStack adds the stack trace of the current goroutine to the log, as "function (file:line)" entries.
+// The stack starts at the call site of the logging function (frames of this package are skipped, like in WithSrc),
+// after skipping the given number of frames (for ex: 1 to start at the caller of a logging helper).
+// See also DefaultLogger.StackLevel to add stack traces automatically.
+func WithStack(skip int) LogOption {
+	return func(l *Log) { l.Data[DataKeyStack] = stackFrom(skip + l.callerSkip) }
+}
+
+// errorChain returns the messages of the errors wrapped by err (depth-first).
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		var wrapped []error
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			wrapped = e.Unwrap()
+		default:
+			if next := errors.Unwrap(err); next != nil {
+				wrapped = []error{next}
+			}
+		}
+		for _, next := range wrapped {
+			if next == nil {
+				continue
+			}
+			chain = append(chain, next.Error())
+			walk(next)
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// callerStack returns the stack trace of the current goroutine as "function (file:line)" entries,
+// skipping the given number of frames (0 identifies the caller of callerStack).
+func callerStack(skip int) []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame.Function+" ("+frame.File+":"+strconv.Itoa(frame.Line)+")")
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// stackFrom returns the stack trace of the current goroutine (see callerStack)
+// starting at the first frame outside of this package, after skipping the given number of frames.
+func stackFrom(skip int) []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		switch {
+		case stack != nil:
+			stack = append(stack, frame.Function+" ("+frame.File+":"+strconv.Itoa(frame.Line)+")")
+		case strings.HasPrefix(frame.Function, packageFuncPrefix):
+		case skip > 0:
+			skip--
+		default:
+			stack = []string{frame.Function + " (" + frame.File + ":" + strconv.Itoa(frame.Line) + ")"}
+		}
+		if !more {
+			return stack
+		}
+	}
+}
@@ -26,6 +26,7 @@ func main() {
 			logs.WithSrc(),       // store source code location in logs
 		},
 	}
+	defer config.Close() // flush and close log file
 
 	// Get logging func
 	log, err := config.LoggerFunc()
@@ -36,6 +37,9 @@ func main() {
 	// Write a simple log
 	log(logs.NewLog("hey, i'm a log"))
 
+	// Write a log with a level of severity
+	log.Info("hey, i'm an informative log")
+
 	// Write a log with additional data
 	log(logs.NewLog(
 		"hey, i'm another log",
@@ -0,0 +1,306 @@
+package logs
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LogFields writes a log with the given level, message and fields.
+//
+// When the logger only uses features that don't need to inspect logs (writers that don't implement LogWriter,
+// the AsJSON or AsCompactJSON serializer, base options, a fixed prefix and suffix, sequence numbers, OnError),
+// the log is encoded directly into a pooled buffer, without putting the fields in the data map:
+// writing a log this way performs almost no allocations.
+// Otherwise (or if a value cannot be encoded this way), the fields are added to the data map
+// and the log is written like with the other Logger methods, with the same result.
+func (dl *DefaultLogger) LogFields(lvl LogLevel, msg string, fields ...Field) error {
+	fn := dl.loggerFunc()
+	if dl.fastPath {
+		if handled, err := dl.logFieldsFast(lvl, msg, fields); handled {
+			return err
+		}
+	}
+	return fn(NewLog(msg, WithLevel(lvl.String()), WithFields(append([]Field(nil), fields...)...)))
+}
+
+// fastLog holds the pooled state used to write a log with LogFields.
+type fastLog struct {
+	l       Log
+	entries []Field // Data of the log (including the fields), sorted by key
+	buf     []byte
+}
+
+var fastLogPool = sync.Pool{New: func() any { return &fastLog{l: Log{Data: map[string]any{}}} }}
+
+// Buffers larger than this are not kept in the pool, so that a single huge log doesn't hold memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+// canUseFastPath reports whether logs can be written by LogFields without creating a regular log.
+// It is called once, when the logger func of the Logger methods is created.
+func (dl *DefaultLogger) canUseFastPath() bool {
+	if dl.Filter != nil || len(dl.Hooks) > 0 || dl.Redactor != nil || dl.Sampling != nil || dl.CallSiteSampling != nil || dl.RateLimits != nil ||
+		dl.DedupWindow > 0 || dl.TimeFormat != nil || dl.KeyCollisions != CollisionOverwrite || len(dl.Sinks) > 0 || dl.StreamSerializer != nil || dl.Async ||
+		dl.LogPrefixFunc != nil || dl.LogSuffixFunc != nil || dl.StackLevel != LevelUnknown || dl.FingerprintLevel != LevelUnknown || len(dl.Writers) == 0 {
+		return false
+	}
+	if dl.Serializer == nil {
+		return false
+	}
+	serializer := reflect.ValueOf(dl.Serializer).Pointer()
+	if serializer != reflect.ValueOf(AsJSON).Pointer() && serializer != reflect.ValueOf(AsCompactJSON).Pointer() {
+		return false
+	}
+	for _, w := range dl.Writers {
+		if _, ok := w.(LogWriter); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// logFieldsFast writes a log with the fast path of LogFields,
+// it returns false if the log must be written as a regular log instead (nothing is written then).
+func (dl *DefaultLogger) logFieldsFast(lvl LogLevel, msg string, fields []Field) (handled bool, err error) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.closed {
+		return true, ErrLoggerClosed
+	}
+	if _, ok := levelOf(lvl); ok && lvl < dl.MinLevel() {
+		return true, nil
+	}
+	for _, f := range fields {
+		if strings.HasPrefix(f.Key, dataKeyPrefix) {
+			return false, nil // Reserved keys are protected from collisions (see CollisionPolicy)
+		}
+	}
+
+	fl := fastLogPool.Get().(*fastLog)
+	defer fl.release()
+
+	// Apply base options to log
+	fl.l.Message = msg
+	dl.bind(&fl.l)
+	fl.l.Data[DataKeyLevel] = levelValue(lvl)
+	for _, opt := range dl.BaseOptions {
+		opt(&fl.l)
+	}
+
+	if hasLazy(&fl.l) {
+		return false, nil // Lazy values are computed without holding the lock in the regular path
+	}
+
+	// Collect and sort data, in the order of the regular path: base options override fields with the same key
+	fl.entries = append(fl.entries, fields...)
+	for k, v := range fl.l.Data {
+		fl.entries = append(fl.entries, Any(k, v))
+	}
+	if dl.Sequence {
+		dl.seq++
+		fl.entries = append(fl.entries, Int64(DataKeySequence, int64(dl.seq)))
+	}
+	sortFields(fl.entries)
+
+	// Encode log
+	b := append(fl.buf[:0], dl.LogPrefix...)
+	b = append(b, `{"message":`...)
+	b = appendJSONString(b, msg)
+	if len(fl.entries) > 0 {
+		b = append(b, `,"data":{`...)
+		for i, f := range fl.entries {
+			if i+1 < len(fl.entries) && fl.entries[i+1].Key == f.Key {
+				continue // Overridden
+			}
+			if b[len(b)-1] != '{' {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, f.Key)
+			b = append(b, ':')
+			var ok bool
+			if b, ok = appendJSONField(b, f); !ok {
+				if dl.Sequence {
+					dl.seq-- // The regular log gets this number
+				}
+				return false, nil
+			}
+		}
+		b = append(b, '}')
+	}
+	b = append(b, '}')
+	b = append(b, dl.LogSuffix...)
+	b = append(b, '\n')
+	fl.buf = b
+
+	// Write log
+	if _, err := dl.w.write(nil, b); err != nil {
+		if dl.OnError != nil {
+			l := NewLog(msg)
+			for _, f := range fl.entries {
+				l.Data[f.Key] = f.Value()
+			}
+			dl.OnError(l, err)
+		}
+		return true, errWrapper{err}
+	}
+	return true, nil
+}
+
+// release resets the state and puts it back in the pool.
+func (fl *fastLog) release() {
+	for k := range fl.l.Data {
+		delete(fl.l.Data, k)
+	}
+	fl.l.collisions = nil
+	for i := range fl.entries {
+		fl.entries[i] = Field{}
+	}
+	fl.entries = fl.entries[:0]
+	if cap(fl.buf) > maxPooledBufferSize {
+		fl.buf = nil
+	}
+	fastLogPool.Put(fl)
+}
+
+// sortFields sorts fields by key, fields with the same key keep their order.
+func sortFields(fields []Field) {
+	if len(fields) > 32 {
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		return
+	}
+	for i := 1; i < len(fields); i++ { // Insertion sort, doesn't allocate
+		for j := i; j > 0 && fields[j].Key < fields[j-1].Key; j-- {
+			fields[j], fields[j-1] = fields[j-1], fields[j]
+		}
+	}
+}
+
+// appendJSONField appends the JSON representation of the value of a field,
+// the same as the one produced by AsJSON. It returns false if the value cannot be encoded.
+func appendJSONField(b []byte, f Field) ([]byte, bool) {
+	switch f.kind {
+	case fieldString:
+		return appendJSONString(b, f.str), true
+	case fieldInt64, fieldDuration:
+		return strconv.AppendInt(b, f.num, 10), true
+	case fieldFloat64:
+		return appendJSONFloat(b, math.Float64frombits(uint64(f.num)))
+	case fieldBool:
+		return strconv.AppendBool(b, f.num != 0), true
+	case fieldTime:
+		return appendJSONTime(b, time.Unix(0, f.num).In(f.value.(*time.Location)))
+	}
+
+	switch v := f.value.(type) {
+	case string:
+		return appendJSONString(b, v), true
+	case bool:
+		return strconv.AppendBool(b, v), true
+	case int:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int64:
+		return strconv.AppendInt(b, v, 10), true
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case uint64:
+		return strconv.AppendUint(b, v, 10), true
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case float64:
+		return appendJSONFloat(b, v)
+	case time.Duration:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case time.Time:
+		return appendJSONTime(b, v)
+	case nil:
+		return append(b, "null"...), true
+	case Loggable:
+		return b, false // Converted in the regular path, see Loggable
+	}
+
+	// Encode other values like AsJSON does (this allocates)
+	encoded, err := defaultEncoder.marshal(stringify(f.value, false))
+	if err != nil {
+		return b, false
+	}
+	return append(b, encoded...), true
+}
+
+// appendJSONTime appends a time like time.Time.MarshalJSON does.
+func appendJSONTime(b []byte, t time.Time) ([]byte, bool) {
+	if y := t.Year(); y < 0 || y > 9999 {
+		return b, false
+	}
+	b = append(b, '"')
+	b = t.AppendFormat(b, time.RFC3339Nano)
+	return append(b, '"'), true
+}
+
+// appendJSONFloat appends a float like encoding/json does, NaN and infinite numbers cannot be encoded.
+func appendJSONFloat(b []byte, f float64) ([]byte, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, true
+}
+
+// appendJSONString appends a JSON string like encoding/json does (with HTML escaping).
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	start := len(b)
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c == '\b' || c == '\f':
+				// Encoded differently depending on the Go version, let encoding/json decide
+				encoded, _ := json.Marshal(s)
+				return append(b[:start], encoded...)
+			case c < 0x20 || c == '<' || c == '>' || c == '&':
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"math"
+	"time"
+)
+
+// Field is a typed data field, used to write logs without allocating (see DefaultLogger.LogFields).
+// Strings, numbers, booleans, durations and times are stored without boxing them in an interface value.
+type Field struct {
+	Key   string
+	kind  fieldKind
+	num   int64  // Integer, boolean, duration, time (in Unix nanoseconds) or float bits
+	str   string // String value
+	value any    // Any value or location of the time
+}
+
+// fieldKind is the type of the value of a field.
+type fieldKind int
+
+const (
+	fieldAny fieldKind = iota
+	fieldString
+	fieldInt64
+	fieldFloat64
+	fieldBool
+	fieldDuration
+	fieldTime
+)
+
+// String returns a string field.
+func String(key, value string) Field { return Field{Key: key, kind: fieldString, str: value} }
+
+// Int returns an integer field.
+func Int(key string, value int) Field { return Int64(key, int64(value)) }
+
+// Int64 returns an integer field.
+func Int64(key string, value int64) Field { return Field{Key: key, kind: fieldInt64, num: value} }
+
+// Float64 returns a floating point number field.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: fieldFloat64, num: int64(math.Float64bits(value))}
+}
+
+// Bool returns a boolean field.
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: fieldBool}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// Duration returns a duration field (serialized as a number of nanoseconds in JSON).
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, kind: fieldDuration, num: int64(value)}
+}
+
+// Time returns a date and time field (serialized in the RFC 3339 format in JSON).
+func Time(key string, value time.Time) Field {
+	if y := value.Year(); y < 1678 || y > 2261 {
+		return Any(key, value) // Out of the range of Unix nanoseconds
+	}
+	return Field{Key: key, kind: fieldTime, num: value.UnixNano(), value: value.Location()}
+}
+
+// Any returns a field holding any value (serialized like the values added with WithData).
+func Any(key string, value any) Field { return Field{Key: key, value: value} }
+
+// Value returns the value of the field.
+func (f Field) Value() any {
+	switch f.kind {
+	case fieldString:
+		return f.str
+	case fieldInt64:
+		return f.num
+	case fieldFloat64:
+		return math.Float64frombits(uint64(f.num))
+	case fieldBool:
+		return f.num != 0
+	case fieldDuration:
+		return time.Duration(f.num)
+	case fieldTime:
+		return time.Unix(0, f.num).In(f.value.(*time.Location))
+	}
+	return f.value
+}
+
+// WithFields adds typed fields to the log.
+func WithFields(fields ...Field) LogOption {
+	return func(l *Log) {
+		for _, f := range fields {
+			l.set(f.Key, f.Value())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package logs
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+const DataKeyFingerprint = dataKeyPrefix + "fingerprint"
+
+// WithFingerprint adds a fingerprint to the log, to group similar errors downstream (like Sentry does),
+// for ex: to count the occurrences of each error in a log search tool.
+// It is a hash (16 hexadecimal characters) of the message template (the message with its words containing digits replaced,
+// for ex: "user 42 not found" becomes "user * not found") and of the function at the top of the stack of the log:
+// the first frame of its stack trace (see WithStack), or else the function where it was created (see WithSrc).
+// The error message is used instead of an empty message.
+// See also DefaultLogger.FingerprintLevel to add fingerprints automatically.
+func WithFingerprint() LogOption {
+	return func(l *Log) { l.Data[DataKeyFingerprint] = fingerprint(l) }
+}
+
+// fingerprint returns the fingerprint of a log (see WithFingerprint).
+func fingerprint(l *Log) string {
+	msg := l.Message
+	if msg == "" {
+		msg, _ = l.Data[DataKeyError].(string)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(messageTemplate(msg)))
+	h.Write([]byte{0})
+	h.Write([]byte(topFunction(l)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// messageTemplate returns the message with its words containing digits replaced by "*" (for ex: IDs and counts).
+func messageTemplate(msg string) string {
+	var b strings.Builder
+	word, hasDigit := 0, false // Start of the current word and whether it contains a digit
+	flush := func(i int) {
+		if hasDigit {
+			b.WriteByte('*')
+		} else {
+			b.WriteString(msg[word:i])
+		}
+	}
+	for i, r := range msg {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			hasDigit = hasDigit || unicode.IsDigit(r)
+			continue
+		}
+		flush(i)
+		b.WriteRune(r)
+		word, hasDigit = i+len(string(r)), false
+	}
+	flush(len(msg))
+	return b.String()
+}
+
+// topFunction returns the function at the top of the stack of a log (without its file and line, which change more often).
+func topFunction(l *Log) string {
+	if stack, ok := l.Data[DataKeyStack].([]string); ok && len(stack) > 0 {
+		if i := strings.LastIndex(stack[0], " ("); i >= 0 {
+			return stack[0][:i]
+		}
+		return stack[0]
+	}
+	if fn, ok := l.Data[DataKeySrcFunction].(string); ok {
+		return fn
+	}
+	if frame, ok := callerFrame(l.callerSkip); ok {
+		return frame.Function
+	}
+	return ""
+}
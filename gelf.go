@@ -0,0 +1,105 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Returns the representation of a log in the Graylog Extended Log Format (GELF) version 1.1.
+// The message is stored as the short message, the timestamp and level are mapped
+// to their GELF equivalent (syslog severities) and the remaining data is stored as additional fields
+// (prefixed with "_", without the "__" prefix of the data keys of this package).
+// Additional field values that are neither strings nor numbers are encoded as JSON strings.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsGELF(l *Log) []byte {
+	b, err := json.Marshal(newGELFLog(l))
+	if err != nil {
+		b, err = json.Marshal(newGELFLog(withoutCycles(l)))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newGELFLog converts a log to a GELF message.
+func newGELFLog(l *Log) map[string]any {
+	out := map[string]any{
+		"version":       "1.1",
+		"host":          gelfHost,
+		"short_message": l.Message,
+	}
+	if l.Message == "" {
+		out["short_message"] = "-" // The short message is mandatory and must not be empty
+	}
+	for k, v := range l.Data {
+		switch k {
+		case DataKeyTimestamp:
+			if t, ok := v.(time.Time); ok {
+				out["timestamp"] = float64(t.UnixNano()/int64(time.Millisecond)) / 1000
+				continue
+			}
+		case DataKeyLevel:
+			if lvl, ok := levelOf(v); ok && lvl != LevelUnknown {
+				out["level"] = syslogSeverities[baseLevel(lvl)]
+				continue
+			}
+		}
+		out[gelfFieldName(k)] = gelfValue(stringify(v, false))
+	}
+	return out
+}
+
+// Holds the host name sent in GELF messages.
+var gelfHost = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}()
+
+// Maps log levels to syslog severities (used by GELF and journald).
+var syslogSeverities = map[LogLevel]int{
+	LevelUnknown: 6,
+	LevelTrace:   7,
+	LevelDebug:   7,
+	LevelInfo:    6,
+	LevelWarn:    4,
+	LevelError:   3,
+	LevelPanic:   2,
+	LevelFatal:   1,
+}
+
+// Matches characters that are not allowed in GELF additional field names.
+var gelfInvalidChars = regexp.MustCompile(`[^\w.\-]`)
+
+// gelfFieldName returns the GELF additional field name for a data key.
+func gelfFieldName(key string) string {
+	name := "_" + gelfInvalidChars.ReplaceAllString(strings.TrimPrefix(key, dataKeyPrefix), "_")
+	if name == "_id" {
+		return "_id_" // "_id" is reserved
+	}
+	return name
+}
+
+// gelfValue returns a GELF additional field value (a string or a number).
+func gelfValue(v any) any {
+	switch v := v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case json.Number:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,48 @@
+package logs
+
+// Hook is notified of the lifecycle of the logs written by a DefaultLogger.
+// Hooks can be used to count logs, send alerts on errors or add and mutate fields.
+type Hook interface {
+	// BeforeSerialize is called for each log that passes the filters, before it is serialized.
+	// The log can be modified.
+	BeforeSerialize(l *Log)
+
+	// AfterWrite is called once per output after writing a log, with the bytes written
+	// (nil when using a stream serializer) and the serialization or write error (if any).
+	// In async mode, it is called from the background goroutine.
+	AfterWrite(l *Log, b []byte, err error)
+}
+
+// DropHook can be implemented by hooks to be notified of the logs dropped before being serialized.
+type DropHook interface {
+	Dropped(l *Log, reason string)
+}
+
+// Reasons for which a log can be dropped, see DropHook.
+const (
+	DropReasonLevel     = "level"      // Below the minimum level
+	DropReasonFilter    = "filter"     // Rejected by the filter
+	DropReasonSampling  = "sampling"   // Sampled out
+	DropReasonRateLimit = "rate_limit" // Exceeds the rate limit of its level
+	DropReasonDuplicate = "duplicate"  // Repeats a log written during the deduplication window
+)
+
+// HookFuncs implements Hook with optional functions.
+type HookFuncs struct {
+	OnBeforeSerialize func(l *Log)
+	OnAfterWrite      func(l *Log, b []byte, err error)
+}
+
+// BeforeSerialize calls OnBeforeSerialize (if set).
+func (h HookFuncs) BeforeSerialize(l *Log) {
+	if h.OnBeforeSerialize != nil {
+		h.OnBeforeSerialize(l)
+	}
+}
+
+// AfterWrite calls OnAfterWrite (if set).
+func (h HookFuncs) AfterWrite(l *Log, b []byte, err error) {
+	if h.OnAfterWrite != nil {
+		h.OnAfterWrite(l, b, err)
+	}
+}
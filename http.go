@@ -0,0 +1,276 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPFieldNames holds the data keys used by the HTTP middleware.
+type HTTPFieldNames struct {
+	Method       string
+	Path         string
+	Status       string
+	Latency      string
+	RemoteAddr   string
+	ResponseSize string
+	RequestBody  string
+}
+
+// Default data keys used by the HTTP middleware.
+var DefaultHTTPFieldNames = HTTPFieldNames{
+	Method:       "method",
+	Path:         "path",
+	Status:       "status",
+	Latency:      "latency",
+	RemoteAddr:   "remote_addr",
+	ResponseSize: "response_size",
+	RequestBody:  "request_body",
+}
+
+// HTTPMiddlewareOption configures the HTTP middleware.
+type HTTPMiddlewareOption func(*httpMiddlewareConfig)
+
+type httpMiddlewareConfig struct {
+	names       HTTPFieldNames
+	maxBodySize int
+	recovery    bool
+	group       string
+}
+
+// WithHTTPFieldNames sets the data keys used by the HTTP middleware.
+func WithHTTPFieldNames(names HTTPFieldNames) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) { c.names = names }
+}
+
+// WithHTTPRequestBody adds (at most maxBytes of) the request body to the logs.
+// The handler still receives the whole body.
+func WithHTTPRequestBody(maxBytes int) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) { c.maxBodySize = maxBytes }
+}
+
+// WithHTTPGroup nests the request data of the logs in a group with the given name (see WithGroup), for ex: "http",
+// so that it doesn't collide with the data added by the application.
+func WithHTTPGroup(name string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) { c.group = name }
+}
+
+// WithHTTPRecovery makes the middleware recover from panics of the handler:
+// the request is logged at panic level with the recovered panic (see WithRecovered)
+// and a 500 status code is sent if the response has not been started.
+// Panics with http.ErrAbortHandler are not recovered (they abort the response on purpose).
+func WithHTTPRecovery() HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) { c.recovery = true }
+}
+
+// HTTPMiddleware returns a middleware that writes a log for each HTTP request
+// with its method, path, status code, latency, remote address and response size.
+// Requests resulting in a server error (status code >= 500) are logged at error level, others at info level.
+// Values extracted from the request context by the registered context fields are also added (see WithContext).
+func HTTPMiddleware(log LoggerFunc, opts ...HTTPMiddlewareOption) func(http.Handler) http.Handler {
+	c := &httpMiddlewareConfig{names: DefaultHTTPFieldNames}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Capture start of request body
+			var body []byte
+			if c.maxBodySize > 0 && r.Body != nil {
+				body, _ = io.ReadAll(io.LimitReader(r.Body, int64(c.maxBodySize)))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			}
+
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			recovered := serveHTTP(next, rw, r, c.recovery)
+			if recovered != nil && !rw.wroteHeader {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+
+			lvl := LevelInfo
+			if recovered != nil {
+				lvl = LevelPanic
+			} else if rw.status >= http.StatusInternalServerError {
+				lvl = LevelError
+			}
+			data := []LogOption{
+				WithData(c.names.Method, r.Method),
+				WithData(c.names.Path, r.URL.Path),
+				WithData(c.names.Status, rw.status),
+				WithData(c.names.Latency, time.Since(start)),
+				WithData(c.names.RemoteAddr, r.RemoteAddr),
+				WithData(c.names.ResponseSize, rw.size),
+			}
+			if body != nil {
+				data = append(data, WithData(c.names.RequestBody, string(body)))
+			}
+			opts := []LogOption{WithLevel(lvl.String()), WithContext(r.Context())}
+			if c.group != "" {
+				opts = append(opts, WithGroup(c.group, data...))
+			} else {
+				opts = append(opts, data...)
+			}
+			if recovered != nil {
+				opts = append(opts, recovered)
+			}
+			log(NewLog(r.Method+" "+r.URL.Path, opts...))
+		})
+	}
+}
+
+// Headers added by WithHTTPRequest and WithHTTPResponse when no header is given.
+// Headers holding credentials (for ex: "Authorization" or "Cookie") are left out on purpose.
+var DefaultHTTPHeaders = []string{"Content-Type", "User-Agent", "Referer", "X-Request-Id", "X-Forwarded-For"}
+
+// WithHTTPRequest adds info about an HTTP request to the log (under DataKeyHTTPRequest):
+// its method, URL (with the password masked), protocol, host, remote address, content length
+// and the given headers (DefaultHTTPHeaders if none are given), when they are present.
+func WithHTTPRequest(r *http.Request, headers ...string) LogOption {
+	return func(l *Log) {
+		info := map[string]any{
+			"method":         r.Method,
+			"url":            r.URL.Redacted(),
+			"proto":          r.Proto,
+			"host":           r.Host,
+			"content_length": r.ContentLength,
+		}
+		if r.RemoteAddr != "" {
+			info["remote_addr"] = r.RemoteAddr
+		}
+		if h := allowedHeaders(r.Header, headers); len(h) > 0 {
+			info["headers"] = h
+		}
+		l.Data[DataKeyHTTPRequest] = info
+	}
+}
+
+// WithHTTPResponse adds info about an HTTP response to the log (under DataKeyHTTPResponse):
+// its status code, protocol, content length and the given headers (DefaultHTTPHeaders if none are given),
+// when they are present.
+func WithHTTPResponse(resp *http.Response, headers ...string) LogOption {
+	return func(l *Log) {
+		info := map[string]any{
+			"status":         resp.StatusCode,
+			"proto":          resp.Proto,
+			"content_length": resp.ContentLength,
+		}
+		if h := allowedHeaders(resp.Header, headers); len(h) > 0 {
+			info["headers"] = h
+		}
+		l.Data[DataKeyHTTPResponse] = info
+	}
+}
+
+// allowedHeaders returns the values of the allowed headers (multiple values are joined with commas).
+func allowedHeaders(h http.Header, allowed []string) map[string]any {
+	if len(allowed) == 0 {
+		allowed = DefaultHTTPHeaders
+	}
+	out := map[string]any{}
+	for _, name := range allowed {
+		if values := h.Values(name); len(values) > 0 {
+			out[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+	}
+	return out
+}
+
+// serveHTTP calls the handler, recovering from its panics if recovery is true:
+// the recovered panic is then returned as a log option (see WithRecovered).
+func serveHTTP(next http.Handler, w http.ResponseWriter, r *http.Request, recovery bool) (recovered LogOption) {
+	if recovery {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				recovered = WithRecovered(v)
+			}
+		}()
+	}
+	next.ServeHTTP(w, r)
+	return nil
+}
+
+// readCloser combines a reader with the closer of the original request body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder records the status code and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status, rr.wroteHeader = status, true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += n
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying response writer does (flushing writes the header).
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		rr.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying response writer does (for ex: for websocket upgrades).
+// The response is then recorded with the status 101 (Switching Protocols) unless a status was already written.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijack: %w", http.ErrNotSupported)
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil && !rr.wroteHeader {
+		rr.status, rr.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, brw, err
+}
+
+// ReadFrom implements io.ReaderFrom, using the one of the underlying response writer if any (for ex: to use sendfile).
+func (rr *responseRecorder) ReadFrom(r io.Reader) (int64, error) {
+	rr.wroteHeader = true
+	if rf, ok := rr.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(r)
+		rr.size += int(n)
+		return n, err
+	}
+	return io.Copy(writerOnly{rr}, r)
+}
+
+// Push implements http.Pusher if the underlying response writer does.
+func (rr *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rr.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// writerOnly hides the methods of a writer other than Write (so that io.Copy doesn't call ReadFrom again).
+type writerOnly struct{ io.Writer }
+
+// Unwrap returns the underlying response writer (used by http.ResponseController).
+func (rr *responseRecorder) Unwrap() http.ResponseWriter { return rr.ResponseWriter }
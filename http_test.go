@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPMiddlewareSupportsHijacking(t *testing.T) {
+	logged := make(chan *Log, 1)
+	mw := HTTPMiddleware(func(l *Log) error { logged <- l; return nil })
+	srv := httptest.NewServer(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("response writer doesn't implement http.Hijacker")
+			return
+		}
+		conn, brw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		brw.Flush()
+	})))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if l := <-logged; l.Data[DefaultHTTPFieldNames.Status] != http.StatusSwitchingProtocols {
+		t.Fatalf("logged status = %v, want 101", l.Data[DefaultHTTPFieldNames.Status])
+	}
+}
+
+func TestHTTPMiddlewareRecoveryAfterFlush(t *testing.T) {
+	logged := make(chan *Log, 1)
+	mw := HTTPMiddleware(func(l *Log) error { logged <- l; return nil }, WithHTTPRecovery())
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // Sends the 200 status
+		panic("boom")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	l := <-logged
+	if status := l.Data[DefaultHTTPFieldNames.Status]; status != http.StatusOK {
+		t.Fatalf("logged status = %v, want the status sent by the flush (%d)", status, http.StatusOK)
+	}
+}
@@ -0,0 +1,104 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returns the representation of a log in the native protocol of systemd-journald (see writers.Journald).
+// The message and level are mapped to the MESSAGE and PRIORITY fields (syslog severities),
+// the location in the source code to the CODE_FILE, CODE_LINE and CODE_FUNC fields
+// and the remaining data is stored as fields named after the data keys, in upper case
+// (without the "__" prefix of the data keys of this package, invalid characters are replaced by "_").
+// Values that are not strings are encoded as JSON.
+func AsJournal(l *Log) []byte {
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", l.Message)
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", journalIdentifier)
+	for _, k := range sortedDataKeys(l) {
+		v := l.Data[k]
+		switch k {
+		case DataKeyLevel:
+			if lvl, ok := levelOf(v); ok {
+				writeJournalField(buf, "PRIORITY", strconv.Itoa(syslogSeverities[baseLevel(lvl)]))
+			}
+		case DataKeyTimestamp:
+			if t, ok := v.(time.Time); ok {
+				v = t.Format(time.RFC3339Nano)
+			}
+		case DataKeySrcFunction:
+			writeJournalField(buf, "CODE_FUNC", fmt.Sprint(v))
+			continue
+		case DataKeySrcFileLine:
+			s := fmt.Sprint(v)
+			if i := strings.LastIndexByte(s, ':'); i >= 0 {
+				writeJournalField(buf, "CODE_FILE", s[:i])
+				writeJournalField(buf, "CODE_LINE", s[i+1:])
+				continue
+			}
+		}
+		writeJournalField(buf, journalFieldName(k), journalValue(v))
+	}
+	return buf.Bytes()
+}
+
+// Holds the identifier of the program sent in journal entries (the name of the executable).
+var journalIdentifier = filepath.Base(os.Args[0])
+
+// writeJournalField writes a field in the journal native protocol,
+// values containing line breaks are written with their length (as a 64-bit little-endian integer).
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// Matches characters that are not allowed in journal field names.
+var journalInvalidChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// journalFieldName returns the journal field name for a data key.
+// Names must not start with "_" (reserved for trusted fields) or a digit and are at most 64 characters long.
+func journalFieldName(key string) string {
+	name := journalInvalidChars.ReplaceAllString(strings.ToUpper(strings.TrimPrefix(key, dataKeyPrefix)), "_")
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journalValue returns the string stored in a journal field for a data value.
+func journalValue(v any) string {
+	v = stringify(v, true)
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sprint(v)
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return s // For ex: a time.Time
+	}
+	return string(b)
+}
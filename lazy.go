@@ -0,0 +1,63 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// WithLazy adds a value computed by the given function to the log, for ex: to dump a large structure in debug logs.
+// With a DefaultLogger, the function is only called if the log is written,
+// once it passed the minimum level, the filter, sampling and rate limits (the filter sees an unevaluated value).
+// It is called without holding the lock of the logger, so a slow function only delays the log holding it,
+// but it must not write logs with the same logger.
+// Otherwise, it is called when the log is first serialized.
+// A panic of the function is recovered and its value is stored instead (as "<panic: ...>").
+func WithLazy(key string, fn func() any) LogOption {
+	return func(l *Log) { l.set(key, &lazyValue{fn: fn}) }
+}
+
+// lazyValue is a data value computed when first needed (see WithLazy).
+type lazyValue struct {
+	fn   func() any
+	once sync.Once
+	v    any
+}
+
+// value returns the value, calling the function the first time.
+func (lv *lazyValue) value() any {
+	lv.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				lv.v = fmt.Sprintf("<panic: %v>", r)
+			}
+		}()
+		lv.v = logValue(lv.fn(), 0)
+	})
+	return lv.v
+}
+
+// MarshalJSON encodes the value, for logs serialized without being written by a DefaultLogger.
+func (lv *lazyValue) MarshalJSON() ([]byte, error) { return json.Marshal(stringify(lv.value(), false)) }
+
+// String formats the value, for logs formatted without being written by a DefaultLogger.
+func (lv *lazyValue) String() string { return fmt.Sprint(lv.value()) }
+
+// hasLazy reports whether the log holds lazy values.
+func hasLazy(l *Log) bool {
+	for _, v := range l.Data {
+		if _, ok := v.(*lazyValue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evalLazy replaces the lazy values of the log by their value.
+func evalLazy(l *Log) {
+	for k, v := range l.Data {
+		if lv, ok := v.(*lazyValue); ok {
+			l.Data[k] = lv.value()
+		}
+	}
+}
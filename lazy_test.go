@@ -0,0 +1,55 @@
+package logs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLazyOnlyCalledForWrittenLogs(t *testing.T) {
+	var buf bytes.Buffer
+	dl := &DefaultLogger{Writers: []io.Writer{&buf}, Serializer: AsJSON}
+	dl.SetMinLevel(LevelInfo)
+
+	calls := 0
+	fn := func() any { calls++; return calls }
+	dl.Debug("dropped", WithLazy("n", fn))
+	if calls != 0 {
+		t.Fatalf("lazy function called %d times for a dropped log", calls)
+	}
+	dl.Info("written", WithLazy("n", fn))
+	if calls != 1 {
+		t.Fatalf("lazy function called %d times for a written log, want 1", calls)
+	}
+	if !strings.Contains(buf.String(), `"n":1`) {
+		t.Fatalf("lazy value missing from output: %s", buf.String())
+	}
+}
+
+func TestWithLazyCanLogWithSameLogger(t *testing.T) {
+	var buf bytes.Buffer
+	dl := &DefaultLogger{Writers: []io.Writer{&buf}, Serializer: AsJSON}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dl.Info("outer", WithLazy("k", func() any { dl.Info("inner"); return 1 }))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging from a lazy function deadlocked")
+	}
+	if !strings.Contains(buf.String(), "inner") || !strings.Contains(buf.String(), "outer") {
+		t.Fatalf("missing logs: %s", buf.String())
+	}
+}
+
+func TestWithLazyRecoversPanics(t *testing.T) {
+	l := NewLog("msg", WithLazy("k", func() any { panic("boom") }))
+	if got := string(AsJSON(l)); !strings.Contains(got, `panic: boom`) {
+		t.Fatalf("got %s", got)
+	}
+}
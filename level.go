@@ -0,0 +1,174 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LogLevel represents the severity of a log.
+// Severity of logs could range anywhere between simple debug info to critical errors.
+// Built-in levels are spaced out so that custom levels can be registered between them (see RegisterLevel).
+// Their values are part of the API (they can be stored as numbers), they changed once (see CHANGELOG.md).
+type LogLevel int
+
+// Represents the level of severity of a log.
+const (
+	LevelUnknown LogLevel = 0  // Only for temporary use, like context.TODO()
+	LevelTrace   LogLevel = 5  // Fine-grained tracing (more verbose than debug)
+	LevelDebug   LogLevel = 10 // Debug (usually not meant to be kept in production)
+	LevelInfo    LogLevel = 20 // Informative data
+	LevelWarn    LogLevel = 30 // Warnings
+	LevelError   LogLevel = 40 // Internal errors
+	LevelPanic   LogLevel = 50 // Panics
+	LevelFatal   LogLevel = 60 // Fatal errors, after which the program exits (see LoggerFunc.Fatal)
+)
+
+// Holds textual representations of the log levels (including custom ones).
+var (
+	levelsMu     sync.RWMutex
+	levelLabels  = map[LogLevel]string{}
+	levelsSorted []LogLevel // Built-in levels, sorted by severity
+	labelLevels  = map[string]LogLevel{}
+	levelValues  = map[LogLevel]any{} // Labels as interface values, so that storing them in logs doesn't allocate
+)
+
+func init() {
+	for lvl, label := range map[LogLevel]string{
+		LevelUnknown: "UNKNOWN",
+		LevelTrace:   "TRACE",
+		LevelDebug:   "DEBUG",
+		LevelInfo:    "INFO",
+		LevelWarn:    "WARN",
+		LevelError:   "ERROR",
+		LevelPanic:   "PANIC",
+		LevelFatal:   "FATAL",
+	} {
+		if err := RegisterLevel(lvl, label); err != nil {
+			panic(err)
+		}
+	}
+	levelsSorted = Levels()
+}
+
+// RegisterLevel registers a custom level with the given label (for ex: RegisterLevel(25, "NOTICE")),
+// its value determines how it compares with other levels (for ex: to apply a minimum level).
+// Labels are upper-cased, an error is returned if the level or label is already registered.
+func RegisterLevel(lvl LogLevel, label string) error {
+	label = strings.ToUpper(label)
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	if existing, ok := levelLabels[lvl]; ok {
+		return fmt.Errorf("log level %d is already registered as %q", int(lvl), existing)
+	}
+	if _, ok := labelLevels[label]; ok || label == "" {
+		return fmt.Errorf("log level label %q is empty or already registered", label)
+	}
+	levelLabels[lvl], labelLevels[label], levelValues[lvl] = label, lvl, label
+	return nil
+}
+
+// Levels returns the registered levels (including built-in ones) sorted by severity.
+func Levels() []LogLevel {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	levels := make([]LogLevel, 0, len(levelLabels))
+	for lvl := range levelLabels {
+		levels = append(levels, lvl)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	return levels
+}
+
+// ParseLevel returns the registered level with the given label (case-insensitive, for ex: "warn").
+func ParseLevel(label string) (LogLevel, error) {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	if lvl, ok := labelLevels[strings.ToUpper(label)]; ok {
+		return lvl, nil
+	}
+	return LevelUnknown, fmt.Errorf("unknown log level %q", label)
+}
+
+// String returns the textual representation of a level,
+// unregistered levels are represented by their value (for ex: "LEVEL(12)").
+func (lvl LogLevel) String() string {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	if label, ok := levelLabels[lvl]; ok {
+		return label
+	}
+	return "LEVEL(" + strconv.Itoa(int(lvl)) + ")"
+}
+
+// levelValue returns the label of a level as an interface value (see WithLevel).
+func levelValue(lvl LogLevel) any {
+	levelsMu.RLock()
+	v, ok := levelValues[lvl]
+	levelsMu.RUnlock()
+	if !ok {
+		return lvl.String()
+	}
+	return v
+}
+
+// MarshalText returns the label of the level.
+func (lvl LogLevel) MarshalText() ([]byte, error) { return []byte(lvl.String()), nil }
+
+// UnmarshalText parses a level label (see ParseLevel).
+func (lvl *LogLevel) UnmarshalText(b []byte) error {
+	parsed, err := ParseLevel(string(b))
+	if err != nil {
+		return err
+	}
+	*lvl = parsed
+	return nil
+}
+
+// MarshalJSON returns the label of the level as a JSON string.
+func (lvl LogLevel) MarshalJSON() ([]byte, error) { return json.Marshal(lvl.String()) }
+
+// UnmarshalJSON parses a level from a JSON string (its label) or number (its value).
+func (lvl *LogLevel) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*lvl = LogLevel(n)
+		return nil
+	}
+	var label string
+	if err := json.Unmarshal(b, &label); err != nil {
+		return err
+	}
+	return lvl.UnmarshalText([]byte(label))
+}
+
+// baseLevel returns the closest built-in level at or below the given level (LevelUnknown if there is none),
+// for ex: to map custom levels to the levels of other logging systems.
+func baseLevel(lvl LogLevel) LogLevel {
+	base := LevelUnknown
+	for _, builtin := range levelsSorted {
+		if builtin <= lvl {
+			base = builtin
+		}
+	}
+	return base
+}
+
+// levelOf returns the level represented by a log data value.
+// The value can either be a registered LogLevel or its textual representation (as stored by WithLevel).
+func levelOf(v any) (LogLevel, bool) {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	switch v := v.(type) {
+	case LogLevel:
+		_, ok := levelLabels[v]
+		return v, ok
+	case string:
+		lvl, ok := labelLevels[v]
+		return lvl, ok
+	}
+	return LevelUnknown, false
+}
@@ -0,0 +1,70 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinLevelValues(t *testing.T) {
+	// Level values can be stored as numbers, changing them is a breaking change (see CHANGELOG.md)
+	want := map[LogLevel]int{
+		LevelUnknown: 0,
+		LevelTrace:   5,
+		LevelDebug:   10,
+		LevelInfo:    20,
+		LevelWarn:    30,
+		LevelError:   40,
+		LevelPanic:   50,
+		LevelFatal:   60,
+	}
+	for lvl, value := range want {
+		if int(lvl) != value {
+			t.Errorf("%s = %d, want %d", lvl, int(lvl), value)
+		}
+	}
+
+	ordered := []LogLevel{LevelUnknown, LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelPanic, LevelFatal}
+	if got := Levels(); !reflect.DeepEqual(got, ordered) {
+		t.Errorf("levels = %v, want %v", got, ordered)
+	}
+}
+
+func TestLevelJSON(t *testing.T) {
+	b, err := json.Marshal(LevelWarn)
+	if err != nil || string(b) != `"WARN"` {
+		t.Fatalf("got %s (%v), want \"WARN\"", b, err)
+	}
+	for _, in := range []string{`"warn"`, `30`} {
+		var lvl LogLevel
+		if err := json.Unmarshal([]byte(in), &lvl); err != nil || lvl != LevelWarn {
+			t.Errorf("%s: got %v (%v), want WARN", in, lvl, err)
+		}
+	}
+}
+
+func TestLevelHandlerPut(t *testing.T) {
+	tests := []struct {
+		body       string
+		wantStatus int
+		wantLevel  LogLevel
+	}{
+		{body: `{"level":"DEBUG"}`, wantStatus: http.StatusOK, wantLevel: LevelDebug},
+		{body: "DEBUG", wantStatus: http.StatusOK, wantLevel: LevelDebug},
+		{body: `{}`, wantStatus: http.StatusBadRequest, wantLevel: LevelInfo},
+		{body: `{"level":null}`, wantStatus: http.StatusBadRequest, wantLevel: LevelInfo},
+		{body: `{"level":"LOUD"}`, wantStatus: http.StatusBadRequest, wantLevel: LevelInfo},
+	}
+	for _, tt := range tests {
+		var lv LevelVar
+		lv.Set(LevelInfo)
+		rec := httptest.NewRecorder()
+		LevelHandler(&lv).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(tt.body)))
+		if rec.Code != tt.wantStatus || lv.Level() != tt.wantLevel {
+			t.Errorf("PUT %s: status %d and level %v, want %d and %v", tt.body, rec.Code, lv.Level(), tt.wantStatus, tt.wantLevel)
+		}
+	}
+}
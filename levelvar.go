@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelVar holds a level that can be changed at runtime, safely for concurrent use.
+// It can be shared by several loggers (see DefaultLogger.LevelVar) to change their minimum level at once.
+// The zero value holds LevelUnknown.
+type LevelVar struct {
+	v int32 // Accessed atomically
+}
+
+// Level returns the current level.
+func (lv *LevelVar) Level() LogLevel { return LogLevel(atomic.LoadInt32(&lv.v)) }
+
+// Set sets the level.
+func (lv *LevelVar) Set(lvl LogLevel) { atomic.StoreInt32(&lv.v, int32(lvl)) }
+
+// String returns the label of the current level.
+func (lv *LevelVar) String() string { return lv.Level().String() }
+
+// MarshalText returns the label of the current level.
+func (lv *LevelVar) MarshalText() ([]byte, error) { return lv.Level().MarshalText() }
+
+// UnmarshalText sets the level from its label (see ParseLevel).
+func (lv *LevelVar) UnmarshalText(b []byte) error {
+	lvl, err := ParseLevel(string(b))
+	if err != nil {
+		return err
+	}
+	lv.Set(lvl)
+	return nil
+}
+
+// LevelHandler returns an HTTP handler to read and change a level at runtime (for ex: mounted on /loglevel),
+// so operators can enable debug logs without restarting.
+//
+// GET requests return the current level as JSON (for ex: {"level":"INFO"}).
+// PUT requests set the level from a JSON body with the same format or a plain-text label (for ex: "DEBUG"),
+// and return the new level (a body without a level is rejected with a 400 status).
+func LevelHandler(lv *LevelVar) http.Handler {
+	type levelBody struct {
+		Level LogLevel `json:"level"`
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			b, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var lvl LogLevel
+			if trimmed := strings.TrimSpace(string(b)); strings.HasPrefix(trimmed, "{") {
+				var body struct {
+					Level *LogLevel `json:"level"` // Nil if missing, rather than LevelUnknown
+				}
+				if err = json.Unmarshal(b, &body); err == nil && body.Level == nil {
+					err = errors.New("missing level")
+				} else if err == nil {
+					lvl = *body.Level
+				}
+			} else {
+				err = lvl.UnmarshalText([]byte(trimmed))
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			lv.Set(lvl)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levelBody{Level: lv.Level()})
+	})
+}
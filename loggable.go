@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Loggable is implemented by types controlling their own representation in logs,
+// for ex: a user type logging its ID and role (but never its password hash):
+//
+//	func (u User) LogData() map[string]any { return map[string]any{"id": u.ID, "role": u.Role} }
+//
+// WithData (and the other data options, including WithFields), WithContext and the slog handler
+// store the returned data instead of the value itself.
+// Loggable values nested in the returned data or held in slices and maps are converted too (but not in struct fields).
+// The method is named differently than the one of slog.LogValuer, so that a type can implement both.
+type Loggable interface {
+	LogData() map[string]any
+}
+
+// Nesting depth after which the data of Loggable values is not converted anymore (in case of a cycle).
+const maxLoggableDepth = 10
+
+var loggableType = reflect.TypeOf((*Loggable)(nil)).Elem()
+
+// logValue returns the data of a Loggable value (see Loggable), or the value itself.
+// Loggable values held in slices, arrays and maps are converted too (the containers are copied then).
+// Nil pointers are stored as nil, a panic of the LogData method is recovered and its value is stored instead (as "<panic: ...>").
+func logValue(v any, depth int) any {
+	out, _ := convertLoggable(v, depth)
+	return out
+}
+
+// convertLoggable converts a value like logValue, it reports whether the value was converted.
+func convertLoggable(v any, depth int) (out any, converted bool) {
+	if v == nil || depth >= maxLoggableDepth {
+		return v, false
+	}
+	if lv, ok := v.(Loggable); ok {
+		return loggableData(lv, depth), true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !mayHoldLoggable(rv.Type().Elem()) || (rv.Kind() == reflect.Slice && rv.IsNil()) {
+			return v, false
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			var ok bool
+			items[i], ok = convertLoggable(rv.Index(i).Interface(), depth+1)
+			converted = converted || ok
+		}
+		if converted {
+			return items, true
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || !mayHoldLoggable(rv.Type().Elem()) || rv.IsNil() {
+			return v, false
+		}
+		entries := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			var ok bool
+			entries[iter.Key().String()], ok = convertLoggable(iter.Value().Interface(), depth+1)
+			converted = converted || ok
+		}
+		if converted {
+			return entries, true
+		}
+	}
+	return v, false
+}
+
+// loggableData returns the data of a Loggable value, with the Loggable values it holds converted.
+func loggableData(lv Loggable, depth int) (out any) {
+	if rv := reflect.ValueOf(lv); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			out = fmt.Sprintf("<panic: %v>", r)
+		}
+	}()
+	data := lv.LogData()
+	converted := make(map[string]any, len(data)) // Copied so that the map of the value is not modified
+	for k, v := range data {
+		converted[k] = logValue(v, depth+1)
+	}
+	return converted
+}
+
+// mayHoldLoggable reports whether values of the given type can be or hold Loggable values.
+func mayHoldLoggable(t reflect.Type) bool {
+	switch {
+	case t.Kind() == reflect.Interface || t.Implements(loggableType):
+		return true
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map:
+		return mayHoldLoggable(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package logs
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID       int
+	Password string
+}
+
+func (u testUser) LogData() map[string]any { return map[string]any{"id": u.ID} }
+
+type testAccount struct{ Owner *testUser }
+
+func (a *testAccount) LogData() map[string]any { return map[string]any{"owner": a.Owner} }
+
+func TestLogValue(t *testing.T) {
+	user := testUser{ID: 1, Password: "SECRET"}
+	tests := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{"plain value", 42, 42},
+		{"loggable", user, map[string]any{"id": 1}},
+		{"nil pointer", (*testAccount)(nil), nil},
+		{"nested loggable", &testAccount{Owner: &user}, map[string]any{"owner": map[string]any{"id": 1}}},
+		{"slice of any", []any{"x", user}, []any{"x", map[string]any{"id": 1}}},
+		{"typed slice", []testUser{user}, []any{map[string]any{"id": 1}}},
+		{"array", [1]testUser{user}, []any{map[string]any{"id": 1}}},
+		{"map", map[string]any{"u": user, "n": 2}, map[string]any{"u": map[string]any{"id": 1}, "n": 2}},
+		{"nested containers", map[string][]any{"users": {user}}, map[string]any{"users": []any{map[string]any{"id": 1}}}},
+		{"slice without loggable", []any{"x", 1}, []any{"x", 1}},
+		{"strings", []string{"x"}, []string{"x"}},
+		{"map with non string keys", map[int]any{1: user}, map[int]any{1: user}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logValue(tt.value, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogValueDoesNotModifyContainers(t *testing.T) {
+	values := []any{testUser{ID: 1}}
+	logValue(values, 0)
+	if _, ok := values[0].(testUser); !ok {
+		t.Fatalf("slice modified: %#v", values)
+	}
+}
+
+func TestLogValueRecoversPanics(t *testing.T) {
+	got := logValue(panickingLoggable{}, 0)
+	if s, ok := got.(string); !ok || !strings.Contains(s, "<panic: boom>") {
+		t.Fatalf("got %#v", got)
+	}
+}
+
+type panickingLoggable struct{}
+
+func (panickingLoggable) LogData() map[string]any { panic("boom") }
+
+func TestWithDataConvertsLoggable(t *testing.T) {
+	l := NewLog("msg", WithData("users", []testUser{{ID: 1, Password: "SECRET"}}))
+	if got := string(AsJSON(l)); strings.Contains(got, "SECRET") || !strings.Contains(got, `"users":[{"id":1}]`) {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestWithContextConvertsLoggable(t *testing.T) {
+	type ctxKey struct{}
+	RegisterContextField("test_user", func(ctx context.Context) any { return ctx.Value(ctxKey{}) })
+	ctx := context.WithValue(context.Background(), ctxKey{}, testUser{ID: 1, Password: "SECRET"})
+	l := NewLog("msg", WithContext(ctx))
+	if got := string(AsJSON(l)); strings.Contains(got, "SECRET") || !strings.Contains(got, `"test_user":{"id":1}`) {
+		t.Fatalf("got %s", got)
+	}
+}
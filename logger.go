@@ -0,0 +1,989 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// LoggerFunc writes a log.
+type LoggerFunc func(*Log) error
+
+// Logger writes logs, it provides shortcuts to write logs with a level of severity.
+type Logger interface {
+	Log(l *Log) error
+	Trace(msg string, opts ...LogOption) error
+	Debug(msg string, opts ...LogOption) error
+	Info(msg string, opts ...LogOption) error
+	Warn(msg string, opts ...LogOption) error
+	Error(msg string, opts ...LogOption) error
+	Panic(msg string, opts ...LogOption) error
+	Fatal(msg string, opts ...LogOption)
+}
+
+// exit terminates the program after fatal logs (replaced in tests).
+var exit = os.Exit
+
+// Both LoggerFunc and *DefaultLogger implement Logger.
+var (
+	_ Logger = LoggerFunc(nil)
+	_ Logger = (*DefaultLogger)(nil)
+)
+
+// Log writes the given log, it allows LoggerFunc to implement Logger.
+func (fn LoggerFunc) Log(l *Log) error { return fn(l) }
+
+// Trace writes a log with the given message at trace level.
+func (fn LoggerFunc) Trace(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelTrace, msg, opts)
+}
+
+// Debug writes a log with the given message at debug level.
+func (fn LoggerFunc) Debug(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelDebug, msg, opts)
+}
+
+// Info writes a log with the given message at info level.
+func (fn LoggerFunc) Info(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelInfo, msg, opts)
+}
+
+// Warn writes a log with the given message at warning level.
+func (fn LoggerFunc) Warn(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelWarn, msg, opts)
+}
+
+// Error writes a log with the given message at error level.
+func (fn LoggerFunc) Error(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelError, msg, opts)
+}
+
+// Panic writes a log with the given message at panic level.
+// It does not panic, it is meant to report panics (for ex: after recovering from one).
+func (fn LoggerFunc) Panic(msg string, opts ...LogOption) error {
+	return fn.logAt(LevelPanic, msg, opts)
+}
+
+// Fatal writes a log with the given message at fatal level, then exits the program with status 1.
+// The writers are not flushed, use DefaultLogger.Fatal to make sure buffered logs are written.
+func (fn LoggerFunc) Fatal(msg string, opts ...LogOption) {
+	fn.logAt(LevelFatal, msg, opts)
+	exit(1)
+}
+
+// logAt writes a log with the given level, message and options.
+func (fn LoggerFunc) logAt(lvl LogLevel, msg string, opts []LogOption) error {
+	return fn(NewLog(msg, append([]LogOption{WithLevel(lvl.String())}, opts...)...))
+}
+
+// With returns a logger func that adds the data of the given options to logs before writing them,
+// for ex: the name of a subsystem or a request ID.
+// Data already present in the log is kept (options given at the call site have priority).
+// The derived logger func writes logs with the parent one, so they share writers and locks.
+func (fn LoggerFunc) With(opts ...LogOption) LoggerFunc {
+	return func(l *Log) error {
+		preset := NewLog("", opts...)
+		for k, v := range preset.Data {
+			if _, ok := l.Data[k]; !ok {
+				l.Data[k] = v
+			}
+		}
+		return fn(l)
+	}
+}
+
+// Named returns a logger func that tags logs with the given component name before writing them.
+// The parent logger func is left untouched.
+// Nested calls produce dotted names, for ex: log.Named("auth").Named("session") tags logs with "auth.session".
+func (fn LoggerFunc) Named(name string) LoggerFunc {
+	return func(l *Log) error {
+		component := name
+		if sub, ok := l.Data[DataKeyComponent].(string); ok && sub != "" {
+			component += "." + sub
+		}
+		l.Data[DataKeyComponent] = component
+		return fn(l)
+	}
+}
+
+// DefaultLogger holds the configuration used to write logs.
+// Logs can be written to any io.Writer (files, in-memory buffers, pipes, network connections, etc.),
+// no file or directory is created by the logger itself.
+type DefaultLogger struct {
+	Writers          []io.Writer            // For ex: stdout and/or file
+	Serializer       Serializer             // For ex: As JSON
+	StreamSerializer StreamSerializer       // For ex: SerializeTo to write large logs without copying them (overrides Serializer for the writers)
+	BaseOptions      []LogOption            // For ex: creation timestamp, source code location
+	LogPrefix        string                 // For ex: "HTTP" or "Server Name"
+	LogSuffix        string                 // For ex: ",\n" to seperate JSON logs by commas and line breaks
+	LogPrefixFunc    func(*Log) string      // For ex: a prefix depending on the log level (overrides LogPrefix)
+	LogSuffixFunc    func(*Log) string      // For ex: no comma after the last log of a batch (overrides LogSuffix)
+	Filter           func(*Log) bool        // For ex: drop health-check logs (return false to drop)
+	OnError          func(*Log, error)      // For ex: increment a metric or write a fallback line to stderr
+	Sequence         bool                   // For ex: true to number logs in order to detect drops in async pipelines
+	Async            bool                   // For ex: true to write logs from a background goroutine so that slow writers don't block callers
+	BufferSize       int                    // For ex: 1024 logs queued at most in async mode (the default), logging blocks when the queue is full
+	Sampling         *SampleRate            // For ex: keep the first 100 identical logs per second, then 1 out of 10
+	CallSiteSampling *CallSiteSampling      // For ex: &CallSiteSampling{} to write the 1st, 10th, 100th... debug logs of each call site
+	Sinks            []Sink                 // For ex: colored output on stdout and JSON in a file (in addition to Writers)
+	WritePolicy      WritePolicy            // For ex: FailFast to stop writing a log once a writer fails (BestEffort by default)
+	CallerSkip       int                    // For ex: 1 to report the caller of a logging helper wrapping this logger (see WithSrc)
+	StackLevel       LogLevel               // For ex: LevelError to add stack traces to error and panic logs (disabled by default, see WithStack)
+	LevelVar         *LevelVar              // For ex: a level shared by several loggers and changed with LevelHandler (see SetMinLevel)
+	Hooks            []Hook                 // For ex: count logs per level or send an alert on errors
+	Redactor         Redactor               // For ex: a FieldRedactor masking passwords and emails before serialization
+	RateLimits       map[LogLevel]RateLimit // For ex: at most 100 ERROR logs per second
+	DedupWindow      time.Duration          // For ex: 10s to collapse identical logs into one with their repeat count (disabled by default)
+	Clock            Clock                  // For ex: a fake clock for deterministic timestamps in tests (see logstest.Clock, the system clock by default)
+	TimeFormat       *TimeFormat            // For ex: &TimeFormat{Layout: TimeUnixMilli} (times are serialized in the RFC 3339 format by default)
+	ValueFormatter   ValueFormatter         // For ex: HumanValues to write durations as "1.2s" and byte sizes as "4.5MiB" in text (fmt is used by default)
+	Diagnostics      *Diagnostics           // For ex: &Diagnostics{} to report failing writers and sinks on stderr (disabled by default)
+	WriteRetry       *RetryPolicy           // For ex: &RetryPolicy{} to retry writes failing with a transient error (disabled by default)
+	KeyCollisions    CollisionPolicy        // For ex: CollisionSuffix to keep every value of a key set several times (CollisionOverwrite by default)
+	FingerprintLevel LogLevel               // For ex: LevelError to add fingerprints to error and panic logs (disabled by default, see WithFingerprint)
+
+	minLevel  LevelVar   // Used when LevelVar is nil, see SetMinLevel
+	mu        sync.Mutex // Shared by all logger funcs so that their writes never interleave
+	asyncOnce sync.Once
+	queue     chan func() // Write operations run by the background goroutine in async mode
+	queueDone chan struct{}
+	closed    bool
+	sampler   sampler
+	sites     callSiteSampler
+	limiter   rateLimiter
+	dedup     deduplicator
+	fnOnce    sync.Once
+	fn        LoggerFunc     // Used by the Logger methods, see loggerFunc
+	w         *writerWrapper // Writers of fn
+	seq       uint64         // Sequence counter of fn
+	fastPath  bool           // Whether LogFields can use its fast path
+}
+
+// Sink is an output with its own serializer and minimum level.
+// Sinks can be used alongside the writers of a logger, for ex: to write errors only to a dedicated file.
+type Sink struct {
+	Writer     io.Writer
+	Serializer Serializer // Defaults to the serializer of the logger
+	MinLevel   LogLevel   // Logs with a lower level are not written to this sink (logs without a level always are)
+}
+
+// ErrLoggerClosed is returned when writing a log (or flushing) with a logger that has been closed.
+var ErrLoggerClosed = errors.New("logger is closed")
+
+// SetMinLevel sets the minimum level of severity of the logs to write (stored in LevelVar if set).
+// Logs with a lower level are dropped, logs without a level are always written.
+// It is safe to call concurrently with logging, the change takes effect immediately.
+func (dl *DefaultLogger) SetMinLevel(lvl LogLevel) { dl.levelVar().Set(lvl) }
+
+// MinLevel returns the minimum level of severity of the logs to write (LevelUnknown by default).
+func (dl *DefaultLogger) MinLevel() LogLevel { return dl.levelVar().Level() }
+
+// levelVar returns the variable holding the minimum level of the logger.
+func (dl *DefaultLogger) levelVar() *LevelVar {
+	if dl.LevelVar != nil {
+		return dl.LevelVar
+	}
+	return &dl.minLevel
+}
+
+// WatchSignal changes the minimum level each time the given signal is received (for ex: syscall.SIGUSR1),
+// rotating through the given levels.
+// This is useful to temporarily enable debug logs in production without redeploying.
+// The returned function stops watching the signal.
+func (dl *DefaultLogger) WatchSignal(sig os.Signal, cycle []LogLevel) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ch:
+				// Move to the level following the current one in the cycle
+				next := 0
+				for i, lvl := range cycle {
+					if lvl == dl.MinLevel() {
+						next = (i + 1) % len(cycle)
+						break
+					}
+				}
+				if len(cycle) > 0 {
+					dl.SetMinLevel(cycle[next])
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// LoggerFunc returns a function that writes logs according to the logger configuration.
+//
+// For each log, the base options are applied first.
+// Then the log is dropped (without being serialized or written)
+// if its level is below the minimum level (see SetMinLevel),
+// if the filter (if any) returns false, if it repeats a log written less than DedupWindow ago,
+// if it is sampled out (see SampleRate and CallSiteSampling) or if it exceeds the rate limit of its level (see RateLimit).
+// Once the deduplication window of a log is over, its last repeat is written with the number of repeats
+// (under DataKeyRepeatCount), the pending repeats are also written by Flush and Close.
+// A stack trace is then added to logs at or above StackLevel (if set and unless they already have one).
+//
+// If Sequence is enabled, each log that passes the filters is then numbered (starting at 1).
+// The counter belongs to the returned function: logger funcs returned by separate calls
+// to LoggerFunc (even on the same logger) have their own counter.
+//
+// Hooks are then notified before the log is serialized and after it is written to each output (see Hook).
+// Like OnError, they are called while the logger lock is held (except in async mode, see below).
+// The redactor (if any) masks sensitive data after the hooks have been notified and before serialization.
+//
+// Serialization and write errors are returned and also reported to OnError (if set),
+// serialization errors wrap ErrSerialize. Writes failing with a transient error are retried with WriteRetry (if set).
+// Writers and sinks that keep failing are also reported by the diagnostics (if set, see Diagnostics).
+// When a log cannot be serialized, a substitute log with the same message and level holding the serialization error
+// (under DataKeySerializeError) is written instead, so the application keeps running and the failure is visible.
+// OnError is called while the logger lock is held, so it must not write logs with the same logger.
+//
+// In async mode, logs are written by a background goroutine: write errors are only reported to OnError
+// (from the background goroutine) and Flush or Close must be called to make sure queued logs are written.
+// The background goroutine writes a copy of each log (data values are shared, not copied),
+// OnError and the AfterWrite hooks receive this copy and are called without holding the logger lock.
+// Once the logger is closed (see Close), logs are dropped and ErrLoggerClosed is returned.
+//
+// The returned function is safe for concurrent use.
+// All functions returned by the same logger share a lock, so logs are never interleaved
+// even when they are written to the same writers from different logger funcs.
+func (dl *DefaultLogger) LoggerFunc() (LoggerFunc, error) {
+	// Init sequence counter (only accessed while holding the lock)
+	var seq uint64
+
+	return dl.newLoggerFunc(dl.newWriterWrapper(), &seq), nil
+}
+
+// newLoggerFunc returns a logger func writing to w and numbering logs with seq.
+func (dl *DefaultLogger) newLoggerFunc(w *writerWrapper, seq *uint64) LoggerFunc {
+	return func(l *Log) error {
+		dl.mu.Lock()
+		defer dl.mu.Unlock()
+		if dl.closed {
+			return ErrLoggerClosed
+		}
+
+		// Apply base options to log
+		dl.bind(l)
+		for _, opt := range dl.BaseOptions {
+			opt(l)
+		}
+
+		// Handle data keys set several times
+		collisionErr := dl.KeyCollisions.resolve(l)
+
+		// Drop log if below min level or filtered out
+		if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok && lvl < dl.MinLevel() {
+			dl.drop(l, DropReasonLevel)
+			return nil
+		}
+		if dl.Filter != nil && !dl.Filter(l) {
+			dl.drop(l, DropReasonFilter)
+			return nil
+		}
+
+		// Drop log if it is a repeat, reporting the repeats of logs whose window is over first
+		if dl.DedupWindow > 0 {
+			dl.dedup.sweep(false)
+			if !dl.dedup.allow(dl.DedupWindow, l, func(summary *Log) error { return dl.write(w, summary, seq) }) {
+				dl.drop(l, DropReasonDuplicate)
+				return nil
+			}
+		}
+
+		// Drop log if sampled out, reporting previously dropped logs first
+		if dl.Sampling != nil {
+			if summary := dl.sampler.summary(dl.Sampling); summary != nil {
+				dl.bind(summary)
+				for _, opt := range dl.BaseOptions {
+					opt(summary)
+				}
+				dl.write(w, summary, seq)
+			}
+			if !dl.sampler.allow(dl.Sampling, l) {
+				dl.drop(l, DropReasonSampling)
+				return nil
+			}
+		}
+
+		// Drop log if sampled out for its call site
+		if dl.CallSiteSampling != nil && !dl.sites.allow(dl.CallSiteSampling, l) {
+			dl.drop(l, DropReasonSampling)
+			return nil
+		}
+
+		// Drop log if rate limited, reporting previously suppressed logs first
+		if dl.RateLimits != nil {
+			ok, summary := dl.limiter.allow(dl.RateLimits, l)
+			if !ok {
+				dl.drop(l, DropReasonRateLimit)
+				return nil
+			}
+			if summary != nil {
+				dl.bind(summary)
+				for _, opt := range dl.BaseOptions {
+					opt(summary)
+				}
+				dl.write(w, summary, seq)
+			}
+		}
+
+		// Compute lazy values once the log is certain to be written, without holding the lock (see WithLazy)
+		if hasLazy(l) {
+			dl.mu.Unlock()
+			evalLazy(l)
+			dl.mu.Lock()
+			if dl.closed {
+				return ErrLoggerClosed
+			}
+		}
+
+		// Add stack trace to severe logs
+		if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok && dl.StackLevel != LevelUnknown && lvl >= dl.StackLevel {
+			if _, exists := l.Data[DataKeyStack]; !exists {
+				WithStack(0)(l)
+			}
+		}
+
+		// Add fingerprint to severe logs
+		if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok && dl.FingerprintLevel != LevelUnknown && lvl >= dl.FingerprintLevel {
+			if _, exists := l.Data[DataKeyFingerprint]; !exists {
+				WithFingerprint()(l)
+			}
+		}
+
+		err := dl.write(w, l, seq)
+		if collisionErr == nil {
+			return err
+		}
+		dl.handleError(l, collisionErr)
+		if ew, ok := err.(errWrapper); ok {
+			return append(errWrapper{collisionErr}, ew...)
+		}
+		return appendErr(errWrapper{collisionErr}, err)
+	}
+}
+
+// bind attaches the settings of the logger used by log options and serializers to a log.
+func (dl *DefaultLogger) bind(l *Log) {
+	l.callerSkip, l.clock, l.timeFormat, l.formatter = dl.CallerSkip, dl.Clock, dl.TimeFormat, dl.ValueFormatter
+}
+
+// loggerFunc returns the logger func used by the Logger methods of the logger, creating it on first use.
+func (dl *DefaultLogger) loggerFunc() LoggerFunc {
+	dl.fnOnce.Do(func() {
+		dl.w = dl.newWriterWrapper()
+		dl.fn = dl.newLoggerFunc(dl.w, &dl.seq)
+		dl.fastPath = dl.canUseFastPath()
+	})
+	return dl.fn
+}
+
+// Log writes the given log, it allows *DefaultLogger to implement Logger.
+// The Logger methods share a single logger func (and thus a single sequence counter),
+// created on first use: the configuration must not change afterwards.
+func (dl *DefaultLogger) Log(l *Log) error { return dl.loggerFunc().Log(l) }
+
+// Trace writes a log with the given message at trace level.
+func (dl *DefaultLogger) Trace(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Trace(msg, opts...)
+}
+
+// Debug writes a log with the given message at debug level.
+func (dl *DefaultLogger) Debug(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Debug(msg, opts...)
+}
+
+// Info writes a log with the given message at info level.
+func (dl *DefaultLogger) Info(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Info(msg, opts...)
+}
+
+// Warn writes a log with the given message at warning level.
+func (dl *DefaultLogger) Warn(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Warn(msg, opts...)
+}
+
+// Error writes a log with the given message at error level.
+func (dl *DefaultLogger) Error(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Error(msg, opts...)
+}
+
+// Panic writes a log with the given message at panic level.
+// It does not panic, it is meant to report panics (for ex: after recovering from one).
+func (dl *DefaultLogger) Panic(msg string, opts ...LogOption) error {
+	return dl.loggerFunc().Panic(msg, opts...)
+}
+
+// Fatal writes a log with the given message at fatal level, closes the logger (flushing its writers, see Close)
+// and exits the program with status 1.
+func (dl *DefaultLogger) Fatal(msg string, opts ...LogOption) {
+	dl.loggerFunc().logAt(LevelFatal, msg, opts)
+	dl.Close()
+	exit(1)
+}
+
+// write numbers (if enabled), serializes and writes a log (in the background in async mode).
+// It must be called while holding the lock.
+func (dl *DefaultLogger) write(w io.Writer, l *Log, seq *uint64) error {
+	// Compute lazy values (already computed for logs written by newLoggerFunc, summaries may hold copies of them)
+	evalLazy(l)
+
+	// Number log
+	if dl.Sequence {
+		*seq++
+		l.Data[DataKeySequence] = *seq
+	}
+
+	// Notify hooks
+	for _, h := range dl.Hooks {
+		h.BeforeSerialize(l)
+	}
+
+	// Mask sensitive data
+	if dl.Redactor != nil {
+		dl.Redactor.Redact(l)
+	}
+
+	// Copy log written in the background, it belongs to the caller again once Log returns
+	if dl.Async {
+		l = l.clone()
+	}
+
+	// Serialize log for each output
+	var errs errWrapper
+	var writes []func() error
+	if len(dl.Writers) > 0 && dl.StreamSerializer != nil {
+		writes = append(writes, func() error { return dl.stream(w, l) })
+	} else if len(dl.Writers) > 0 {
+		write, err := dl.prepareWrite(w, -1, dl.Serializer, l)
+		writes, errs = appendWrite(writes, write), appendErr(errs, err)
+	}
+	for i, sink := range dl.Sinks {
+		if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok && lvl < sink.MinLevel {
+			continue
+		}
+		serializer := sink.Serializer
+		if serializer == nil {
+			serializer = dl.Serializer
+		}
+		write, err := dl.prepareWrite(sink.Writer, i, serializer, l)
+		writes, errs = appendWrite(writes, write), appendErr(errs, err)
+	}
+	for _, err := range errs {
+		dl.handleError(l, err)
+		dl.afterWrite(l, nil, err)
+	}
+
+	// Write log to each output (in the background in async mode)
+	writeAll := func() (errs errWrapper) {
+		for _, write := range writes {
+			if err := write(); err != nil {
+				dl.handleError(l, err)
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+	if dl.Async {
+		if err := dl.enqueue(func() { writeAll() }); err != nil {
+			return err
+		}
+	} else {
+		errs = append(errs, writeAll()...)
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// prepareWrite serializes a log and returns a function writing it to w, surrounded by its prefix and suffix.
+// The returned function notifies the hooks once the log is written.
+// For a sink (with its index, -1 for the writers of the logger whose wrapper does it),
+// transient failures are retried (if enabled) and the result is reported to the diagnostics (if set).
+//
+// If the log cannot be serialized, the serialization error is returned along with a function
+// writing a substitute log instead (see substituteLog), so that the failure is visible in the output.
+func (dl *DefaultLogger) prepareWrite(w io.Writer, sink int, serializer Serializer, l *Log) (func() error, error) {
+	serialized, serializeErr := serialize(serializer, l)
+	written := l
+	if serializeErr != nil {
+		written = substituteLog(l, serializeErr)
+		var err error
+		if serialized, err = serialize(serializer, written); err != nil {
+			if serialized, err = serialize(AsJSON, written); err != nil {
+				return nil, serializeErr
+			}
+		}
+	}
+	prefix, suffix := dl.prefix(l), dl.suffix(l)
+	b := make([]byte, 0, len(prefix)+len(serialized)+len(suffix)+1)
+	b = append(append(append(append(b, prefix...), serialized...), suffix...), '\n')
+	return func() error {
+		var err error
+		if sink < 0 {
+			_, err = writeLog(w, written, b)
+		} else {
+			_, err = dl.WriteRetry.writeLog(w, written, b)
+			if dl.Diagnostics != nil {
+				dl.Diagnostics.observe(outputName("sinks", sink, w), err)
+			}
+		}
+		dl.afterWrite(l, b, err)
+		return err
+	}, serializeErr
+}
+
+// stream writes a log to w with the stream serializer, surrounded by its prefix and suffix,
+// without holding the serialized log in memory (w receives several writes per log).
+// It is called while holding the lock (or from the background goroutine in async mode), so logs never interleave.
+//
+// If the stream serializer fails without a write error, the serialization error is returned
+// and a substitute log is written after what has already been written (see substituteLog).
+func (dl *DefaultLogger) stream(w io.Writer, l *Log) error {
+	ew := &errWriter{w: w}
+	var serializeErr error
+	_, err := io.WriteString(ew, dl.prefix(l))
+	if err == nil {
+		err = streamLog(dl.StreamSerializer, l, ew)
+		if err != nil && ew.err == nil {
+			serializeErr = err
+			sub := substituteLog(l, serializeErr)
+			if err = streamLog(dl.StreamSerializer, sub, ew); err != nil && ew.err == nil {
+				_, err = ew.Write(AsJSON(sub))
+			}
+		}
+	}
+	if err == nil {
+		_, err = io.WriteString(ew, dl.suffix(l)+"\n")
+	}
+	if err == nil {
+		err = serializeErr
+	}
+	dl.afterWrite(l, nil, err)
+	return err
+}
+
+// streamLog calls the stream serializer and converts a panic or an error not caused by w into a serialization error.
+func streamLog(ss StreamSerializer, l *Log, w *errWriter) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrSerialize, r)
+		}
+	}()
+	if err = ss(l, w); err != nil && w.err == nil {
+		err = fmt.Errorf("%w: %v", ErrSerialize, err)
+	}
+	return err
+}
+
+// errWriter records the last error returned by the writer it wraps.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(b []byte) (int, error) {
+	n, err := ew.w.Write(b)
+	if err != nil {
+		ew.err = err
+	}
+	return n, err
+}
+
+// writeLog writes a serialized log to w, using WriteLog if w implements LogWriter.
+func writeLog(w io.Writer, l *Log, b []byte) (int, error) {
+	if lw, ok := w.(LogWriter); ok && l != nil {
+		if err := lw.WriteLog(l, b); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.Write(b)
+}
+
+// substituteLog returns the log written instead of a log that cannot be serialized:
+// it has the same message, timestamp, level, source location, component and sequence number
+// and holds the serialization error (other data is dropped).
+func substituteLog(l *Log, err error) *Log {
+	sub := NewLog(l.Message, WithData(DataKeySerializeError, err.Error()))
+	sub.timeFormat, sub.formatter = l.timeFormat, l.formatter
+	for _, k := range []string{DataKeyTimestamp, DataKeyLevel, DataKeySrcFunction, DataKeySrcFileLine, DataKeyComponent, DataKeySequence} {
+		if v, ok := l.Data[k]; ok {
+			sub.Data[k] = v
+		}
+	}
+	return sub
+}
+
+// appendWrite appends a write operation (if any).
+func appendWrite(writes []func() error, write func() error) []func() error {
+	if write == nil {
+		return writes
+	}
+	return append(writes, write)
+}
+
+// appendErr appends an error (if any).
+func appendErr(errs errWrapper, err error) errWrapper {
+	if err == nil {
+		return errs
+	}
+	return append(errs, err)
+}
+
+// drop notifies the hooks implementing DropHook that a log has been dropped.
+func (dl *DefaultLogger) drop(l *Log, reason string) {
+	for _, h := range dl.Hooks {
+		if dh, ok := h.(DropHook); ok {
+			dh.Dropped(l, reason)
+		}
+	}
+}
+
+// afterWrite notifies the hooks that a log has been written (or failed to be).
+func (dl *DefaultLogger) afterWrite(l *Log, b []byte, err error) {
+	for _, h := range dl.Hooks {
+		h.AfterWrite(l, b, err)
+	}
+}
+
+// enqueue queues a write operation for the background goroutine, starting it if needed.
+// It must be called while holding the lock.
+func (dl *DefaultLogger) enqueue(op func()) error {
+	if dl.closed {
+		return ErrLoggerClosed
+	}
+	dl.asyncOnce.Do(func() {
+		size := dl.BufferSize
+		if size <= 0 {
+			size = 1024
+		}
+		dl.queue, dl.queueDone = make(chan func(), size), make(chan struct{})
+		go func() {
+			for op := range dl.queue {
+				op()
+			}
+			close(dl.queueDone)
+		}()
+	})
+	dl.queue <- op
+	return nil
+}
+
+// LogWriter is implemented by writers that need the log along with its serialized bytes,
+// for ex: to use one of its fields as a message key. DefaultLogger calls WriteLog instead of Write on such writers.
+type LogWriter interface {
+	io.Writer
+	WriteLog(l *Log, b []byte) error
+}
+
+// Flusher is implemented by writers buffering data (for ex: bufio.Writer or writers.FileWriter).
+type Flusher interface {
+	Flush() error
+}
+
+// Syncer is implemented by writers that can commit their data to stable storage (for ex: os.File).
+type Syncer interface {
+	Sync() error
+}
+
+// Flush waits for the queued logs to be written (in async mode)
+// and flushes the writers and hooks implementing Flusher.
+func (dl *DefaultLogger) Flush() error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.closed {
+		return ErrLoggerClosed
+	}
+	dl.dedup.sweep(true)
+
+	// Wait for queued logs, the queue is processed in order
+	if dl.queue != nil {
+		done := make(chan struct{})
+		dl.queue <- func() { close(done) }
+		<-done
+	}
+
+	return dl.flushWriters(false)
+}
+
+// Close waits for the queued logs to be written (in async mode),
+// then flushes, syncs and closes the writers of the logger and its sinks
+// (depending on whether they implement Flusher, Syncer and/or io.Closer),
+// and flushes and closes its hooks (if they implement Flusher and/or io.Closer, for ex: to send buffered events).
+// The standard output and error streams are left untouched.
+// Errors are aggregated and returned once all writers have been handled.
+// Logs written afterwards are dropped and ErrLoggerClosed is returned, closing the logger again does nothing.
+func (dl *DefaultLogger) Close() error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.closed {
+		return nil
+	}
+	dl.dedup.sweep(true)
+
+	// Stop background goroutine once it has written the queued logs
+	if dl.queue != nil {
+		close(dl.queue)
+		<-dl.queueDone
+	}
+	dl.closed = true
+
+	return dl.flushWriters(true)
+}
+
+// flushWriters flushes the writers and hooks of the logger, also syncing and closing them if done is true.
+// Wrapped writers (implementing Unwrap() io.Writer) are handled instead of their wrappers.
+// It must be called while holding the lock.
+func (dl *DefaultLogger) flushWriters(done bool) error {
+	var errs errWrapper
+	for _, w := range dl.outputs() {
+		for {
+			u, ok := w.(interface{ Unwrap() io.Writer })
+			if !ok {
+				break
+			}
+			w = u.Unwrap()
+		}
+		if w == os.Stdout || w == os.Stderr {
+			continue
+		}
+		if f, ok := w.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if !done {
+			continue
+		}
+		if s, ok := w.(Syncer); ok {
+			if err := s.Sync(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, h := range dl.Hooks {
+		if f, ok := h.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if c, ok := h.(io.Closer); ok && done {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// outputs returns the writers of the logger, including the writers of its sinks (without duplicates).
+func (dl *DefaultLogger) outputs() []io.Writer {
+	outputs := append([]io.Writer{}, dl.Writers...)
+	for _, sink := range dl.Sinks {
+		dup := false
+		for _, w := range outputs {
+			dup = dup || w == sink.Writer
+		}
+		if !dup {
+			outputs = append(outputs, sink.Writer)
+		}
+	}
+	return outputs
+}
+
+// prefix returns the string to write before the given log.
+func (dl *DefaultLogger) prefix(l *Log) string {
+	if dl.LogPrefixFunc != nil {
+		return dl.LogPrefixFunc(l)
+	}
+	return dl.LogPrefix
+}
+
+// suffix returns the string to write after the given log (before the line break).
+func (dl *DefaultLogger) suffix(l *Log) string {
+	if dl.LogSuffixFunc != nil {
+		return dl.LogSuffixFunc(l)
+	}
+	return dl.LogSuffix
+}
+
+// handleError reports a serialization or write error to the error handler (if any).
+func (dl *DefaultLogger) handleError(l *Log, err error) {
+	if dl.OnError != nil {
+		dl.OnError(l, err)
+	}
+}
+
+// serialize calls the serializer and converts a panic into an error.
+func serialize(s Serializer, l *Log) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrSerialize, r)
+		}
+	}()
+	return s(l), nil
+}
+
+// WritePolicy determines how a logger writes to its writers when one of them fails.
+type WritePolicy int
+
+const (
+	// BestEffort writes to all writers even if some of them fail.
+	// The number of bytes reported is the smallest one among writers
+	// (so a partial write to any writer is visible) and all errors are returned.
+	BestEffort WritePolicy = iota
+
+	// FailFast stops at the first writer that fails (for ex: a failed file write prevents writing to stdout).
+	// The number of bytes reported is the one of the failed write.
+	FailFast
+
+	// RequireAll writes the log to all writers, completing short writes so that no writer is left with a truncated log,
+	// and reports a failure if any writer fails: zero bytes are reported along with all errors.
+	// Writes cannot be undone, so the writers that succeeded keep the log (retrying it may duplicate it there).
+	RequireAll
+)
+
+// writerWrapper is a utility type that implements io.Writer by wrapping one or more io.Writers
+type writerWrapper struct {
+	writers []io.Writer
+	policy  WritePolicy
+	diag    *Diagnostics // Reports the result of each write if not nil
+	retry   *RetryPolicy // Retries transient failures if not nil
+	names   []string     // Names of the writers reported to diag
+}
+
+// newWriterWrapper instanciates a new WriterWrapper.
+func newWriterWrapper(policy WritePolicy, w ...io.Writer) *writerWrapper {
+	return &writerWrapper{writers: w, policy: policy}
+}
+
+// newWriterWrapper returns a wrapper of the writers of the logger, retrying their transient failures (if enabled)
+// and reporting their writes to the diagnostics (if set).
+func (dl *DefaultLogger) newWriterWrapper() *writerWrapper {
+	ww := newWriterWrapper(dl.WritePolicy, dl.Writers...)
+	ww.retry = dl.WriteRetry
+	if dl.Diagnostics != nil {
+		ww.diag = dl.Diagnostics
+		for i, w := range dl.Writers {
+			ww.names = append(ww.names, outputName("writers", i, w))
+		}
+	}
+	return ww
+}
+
+// Write writes b to the underlying writers according to the write policy.
+func (ww *writerWrapper) Write(b []byte) (int, error) { return ww.write(nil, b) }
+
+// WriteLog writes a serialized log to the underlying writers according to the write policy,
+// so that the underlying writers implementing LogWriter receive the log.
+func (ww *writerWrapper) WriteLog(l *Log, b []byte) error {
+	_, err := ww.write(l, b)
+	return err
+}
+
+// write writes b (the serialization of l if not nil) to the underlying writers.
+func (ww *writerWrapper) write(l *Log, b []byte) (int, error) {
+	numBytesWritten := len(b)
+	var errs errWrapper
+	for i, w := range ww.writers {
+		n, err := ww.retry.writeLog(w, l, b)
+		for ww.policy == RequireAll && err == nil && n > 0 && n < len(b) {
+			var more int
+			more, err = w.Write(b[n:])
+			n += more
+			if more == 0 && err == nil {
+				err = io.ErrShortWrite
+			}
+		}
+		if err == nil && n < len(b) {
+			err = io.ErrShortWrite
+		}
+		if n < numBytesWritten {
+			numBytesWritten = n
+		}
+		if ww.diag != nil {
+			ww.diag.observe(ww.names[i], err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			if ww.policy == FailFast {
+				return n, errs
+			}
+		}
+	}
+	if errs != nil {
+		if ww.policy == RequireAll {
+			return 0, errs
+		}
+		return numBytesWritten, errs
+	}
+	return numBytesWritten, nil
+}
+
+// errWrapper is a utility type that wraps one or more errors.
+type errWrapper []error
+
+// Error is the implementation of the error interface.
+// It joins error messages with ", ".
+func (ew errWrapper) Error() string {
+	out := ""
+	for i, err := range ew {
+		if i > 0 {
+			out += ", "
+		}
+		out += err.Error()
+	}
+	return out
+}
+
+// Unwrap returns the wrapped errors.
+func (ew errWrapper) Unwrap() []error { return ew }
+
+// Is reports whether any of the wrapped errors matches the target (see errors.Is),
+// this allows detecting specific writer failures (for ex: os.ErrClosed) with Go versions older than 1.20.
+func (ew errWrapper) Is(target error) bool {
+	for _, err := range ew {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first wrapped error that matches the target and sets the target to it (see errors.As).
+func (ew errWrapper) As(target any) bool {
+	for _, err := range ew {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestAsyncHooksAndOnErrorGetACopyOfTheLog(t *testing.T) {
+	reads := make(chan any, 100)
+	hook := HookFuncs{OnAfterWrite: func(l *Log, b []byte, err error) { reads <- l.Data["i"] }}
+	dl := &DefaultLogger{
+		Writers:    []io.Writer{failingWriter{}},
+		Serializer: AsJSON,
+		Hooks:      []Hook{hook},
+		OnError:    func(l *Log, err error) { reads <- l.Data["i"] },
+		Async:      true,
+	}
+	for i := 0; i < 10; i++ {
+		l := NewLog("msg", WithData("i", i))
+		if err := dl.Log(l); err != nil {
+			t.Fatal(err)
+		}
+		l.Data["i"] = "mutated after the call" // The log belongs to the caller again
+		delete(l.Data, "i")
+	}
+	if err := dl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	close(reads)
+	n := 0
+	for v := range reads {
+		if _, ok := v.(int); !ok {
+			t.Errorf("hook or error handler read %v, want the logged value", v)
+		}
+		n++
+	}
+	if n != 20 {
+		t.Fatalf("%d calls, want 10 hook calls and 10 error handler calls", n)
+	}
+}
+
+// slowWriter writes each log in several small chunks, pausing in between to give other goroutines a chance to interleave.
+type slowWriter struct {
+	buf bytes.Buffer
+}
+
+func (sw *slowWriter) Write(b []byte) (int, error) {
+	for i := 0; i < len(b); i += 8 {
+		end := i + 8
+		if end > len(b) {
+			end = len(b)
+		}
+		sw.buf.Write(b[i:end])
+		runtime.Gosched()
+	}
+	return len(b), nil
+}
+
+func TestConcurrentLogsAreNotInterleaved(t *testing.T) {
+	const goroutines, logsPerGoroutine = 300, 20
+	slow, fast := &slowWriter{}, &bytes.Buffer{}
+	dl := &DefaultLogger{Writers: []io.Writer{slow, fast}, Serializer: AsJSON, Sequence: true}
+	fn, err := dl.LoggerFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < logsPerGoroutine; i++ {
+				// Alternate between the Logger methods and a logger func sharing the same lock
+				if i%2 == 0 {
+					dl.Info("concurrent", WithData("goroutine", g), WithData("i", i))
+				} else {
+					fn.Info("concurrent", WithData("goroutine", g), WithData("i", i))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for name, out := range map[string][]byte{"slow": slow.buf.Bytes(), "fast": fast.Bytes()} {
+		lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+		if len(lines) != goroutines*logsPerGoroutine {
+			t.Fatalf("%s writer: %d lines, want %d", name, len(lines), goroutines*logsPerGoroutine)
+		}
+		for _, line := range lines {
+			var l Log
+			if err := json.Unmarshal([]byte(line), &l); err != nil || l.Message != "concurrent" {
+				t.Fatalf("%s writer: interleaved or corrupted line %q", name, line)
+			}
+		}
+	}
+}
+
+func TestDedupSummaryGetsACopyOfTheLastRepeat(t *testing.T) {
+	var buf bytes.Buffer
+	dl := &DefaultLogger{Writers: []io.Writer{&buf}, Serializer: AsJSON, DedupWindow: time.Hour}
+	for i := 0; i < 3; i++ {
+		l := NewLog("msg", WithData("i", i))
+		if err := dl.Log(l); err != nil {
+			t.Fatal(err)
+		}
+		l.Data["i"] = "mutated after the call" // The log belongs to the caller again
+	}
+	if err := dl.Flush(); err != nil { // Writes the summary of the repeats
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d logs written, want the first log and the summary: %q", len(lines), buf.String())
+	}
+	var summary struct{ Data map[string]any }
+	if err := json.Unmarshal([]byte(lines[1]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Data["i"] != 2.0 || summary.Data[DataKeyRepeatCount] != 2.0 {
+		t.Fatalf("summary = %s, want the last repeat (i=2) with 2 repeats", lines[1])
+	}
+}
@@ -0,0 +1,119 @@
+// Package logstest provides utilities to test code that writes logs.
+package logstest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logs "github.com/ejuju/go-logs"
+)
+
+// Recorder records logs in memory so that tests can assert on them without parsing serialized output.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []*logs.Log
+}
+
+// NewRecorder returns an empty recorder.
+func NewRecorder() *Recorder { return &Recorder{} }
+
+// LoggerFunc returns a logger func recording logs.
+// It is safe for concurrent use.
+func (r *Recorder) LoggerFunc() logs.LoggerFunc {
+	return func(l *logs.Log) error {
+		// Copy log data so that later changes made by the caller are not recorded
+		entry := &logs.Log{Message: l.Message, Data: make(map[string]any, len(l.Data))}
+		for k, v := range l.Data {
+			entry.Data[k] = v
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.entries = append(r.entries, entry)
+		return nil
+	}
+}
+
+// Entries returns the recorded logs, in the order they were written.
+func (r *Recorder) Entries() []*logs.Log {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*logs.Log{}, r.entries...)
+}
+
+// Filter returns the recorded logs with the given level.
+func (r *Recorder) Filter(lvl logs.LogLevel) []*logs.Log {
+	var entries []*logs.Log
+	for _, l := range r.Entries() {
+		if v := l.Data[logs.DataKeyLevel]; v == lvl.String() || v == lvl {
+			entries = append(entries, l)
+		}
+	}
+	return entries
+}
+
+// Reset removes all recorded logs.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// AssertContains marks the test as failed if no recorded log message contains msg.
+func (r *Recorder) AssertContains(t testing.TB, msg string) {
+	t.Helper()
+	entries := r.Entries()
+	messages := make([]string, 0, len(entries))
+	for _, l := range entries {
+		if strings.Contains(l.Message, msg) {
+			return
+		}
+		messages = append(messages, l.Message)
+	}
+	t.Errorf("no log message contains %q, got: %q", msg, messages)
+}
+
+// Clock is a fake clock (see logs.Clock) whose time only changes when the test changes it,
+// so that timestamps are deterministic: use it as the clock of a logs.DefaultLogger.
+// It is safe for concurrent use.
+type Clock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewClock returns a fake clock set to the given time.
+func NewClock(t time.Time) *Clock { return &Clock{now: t} }
+
+// Now returns the time of the clock, then advances it by the step (if any, see SetStep).
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Set sets the time of the clock.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the time of the clock forward by the given duration.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SetStep makes the clock advance by the given duration each time it is read,
+// for ex: so that consecutive logs have distinct (but still deterministic) timestamps.
+func (c *Clock) SetStep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.step = d
+}
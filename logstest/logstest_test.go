@@ -0,0 +1,117 @@
+package logstest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	logs "github.com/ejuju/go-logs"
+)
+
+func TestRecorderRecordsACopyOfLogs(t *testing.T) {
+	r := NewRecorder()
+	l := logs.NewLog("msg", logs.WithData("key", "value"))
+	if err := r.LoggerFunc()(l); err != nil {
+		t.Fatal(err)
+	}
+	l.Data["key"] = "mutated after the call"
+
+	entries := r.Entries()
+	if len(entries) != 1 || entries[0].Message != "msg" || entries[0].Data["key"] != "value" {
+		t.Fatalf("entries = %v, want the log as it was written", entries)
+	}
+}
+
+func TestRecorderIsSafeForConcurrentUse(t *testing.T) {
+	r := NewRecorder()
+	log := r.LoggerFunc()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			log(logs.NewLog(fmt.Sprint("msg ", i)))
+			r.Entries()
+		}(i)
+	}
+	wg.Wait()
+	if n := len(r.Entries()); n != 10 {
+		t.Fatalf("%d entries, want 10", n)
+	}
+}
+
+func TestRecorderFilterAndReset(t *testing.T) {
+	r := NewRecorder()
+	log := r.LoggerFunc()
+	log(logs.NewLog("first error", logs.WithLevel(logs.LevelError.String())))
+	log(logs.NewLog("info", logs.WithLevel(logs.LevelInfo.String())))
+	log(logs.NewLog("second error", logs.WithData(logs.DataKeyLevel, logs.LevelError)))
+	log(logs.NewLog("without level"))
+
+	errs := r.Filter(logs.LevelError)
+	if len(errs) != 2 || errs[0].Message != "first error" || errs[1].Message != "second error" {
+		t.Fatalf("error logs = %v, want the 2 error logs in order", errs)
+	}
+	r.Reset()
+	if n := len(r.Entries()); n != 0 {
+		t.Fatalf("%d entries after reset, want 0", n)
+	}
+}
+
+// fakeTB records the failures of assertions.
+type fakeTB struct {
+	testing.TB
+	failures []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.failures = append(tb.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderAssertContains(t *testing.T) {
+	r := NewRecorder()
+	r.LoggerFunc()(logs.NewLog("user created"))
+
+	tests := []struct {
+		msg      string
+		wantFail bool
+	}{
+		{msg: "user created", wantFail: false},
+		{msg: "created", wantFail: false},
+		{msg: "user deleted", wantFail: true},
+	}
+	for _, tt := range tests {
+		tb := &fakeTB{}
+		r.AssertContains(tb, tt.msg)
+		if failed := len(tb.failures) > 0; failed != tt.wantFail {
+			t.Errorf("AssertContains(%q) failed = %v (%q), want %v", tt.msg, failed, tb.failures, tt.wantFail)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewClock(start)
+	if now := c.Now(); !now.Equal(start) {
+		t.Fatalf("now = %v, want %v", now, start)
+	}
+	if now := c.Now(); !now.Equal(start) {
+		t.Fatalf("now = %v after a read, want %v (no step)", now, start)
+	}
+
+	c.Advance(time.Minute)
+	if now := c.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Fatalf("now = %v after advancing, want %v", now, start.Add(time.Minute))
+	}
+
+	c.Set(start)
+	c.SetStep(time.Second)
+	for i := 0; i < 3; i++ {
+		if now, want := c.Now(), start.Add(time.Duration(i)*time.Second); !now.Equal(want) {
+			t.Fatalf("read %d: now = %v, want %v", i, now, want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LokiWriter sends logs to Grafana Loki with its push API (with JSON payloads), so that logs don't need to be shipped by an agent.
+// It implements LogWriter: use it as a writer of a DefaultLogger (writes of serialized JSON logs are also accepted).
+// Fields must be set before the first write.
+//
+// Each log is sent as a line (its serialization, for ex: with AsJSON) in the stream of its labels:
+// the static labels and the values of the data keys listed in LabelKeys.
+// Labels should have a low cardinality (for ex: the level or the component, not a user ID).
+//
+// Logs are sent by batches from a background goroutine, failed requests are retried with an exponential backoff.
+// Flush or Close must be called to make sure all logs are sent (DefaultLogger.Flush and Close do it).
+// When QueueSize batches are already waiting to be sent, writes block until Loki catches up.
+type LokiWriter struct {
+	Labels        map[string]string // For ex: {"app": "billing", "env": "prod"}
+	LabelKeys     []string          // For ex: DataKeyLevel and DataKeyComponent (DataKeyLevel by default), named without the "__" prefix
+	TenantID      string            // For ex: "team-a" (sent in the X-Scope-OrgID header of multi-tenant Loki deployments)
+	Client        *http.Client      // For ex: a client with a custom transport (a client with a 10s timeout by default)
+	Header        http.Header       // For ex: an "Authorization" header added to all requests
+	BatchSize     int               // For ex: 512 KB of log lines per request (1 MB by default)
+	FlushInterval time.Duration     // For ex: 5s to send incomplete batches periodically (1s by default)
+	QueueSize     int               // For ex: 16 batches waiting to be sent before writes block (4 by default)
+	MaxRetries    int               // For ex: 10 attempts after the first one (3 by default, a negative number disables retries)
+	OnError       func(error)       // For ex: write a fallback line to stderr (errors are ignored by default)
+
+	url       string
+	mu        sync.Mutex
+	batch     *lokiBatch
+	queue     chan lokiQueued
+	inflight  sync.WaitGroup // Writes queueing a batch
+	startOnce sync.Once
+	stopped   chan struct{}
+	closed    bool
+}
+
+// lokiBatch holds the streams of a batch, by labels.
+type lokiBatch struct {
+	streams map[string]*lokiStream
+	size    int // Size of the lines
+}
+
+// lokiStream is a stream of the push API.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // Timestamp (in nanoseconds since the Unix epoch) and line
+}
+
+// lokiQueued is a batch waiting to be sent, done (if not nil) receives the result once it is sent.
+type lokiQueued struct {
+	batch *lokiBatch
+	done  chan error
+}
+
+// Matches characters that are not allowed in Loki label names.
+var lokiInvalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// NewLokiWriter returns a writer sending logs to the push API at the given URL
+// (for ex: "http://localhost:3100/loki/api/v1/push").
+func NewLokiWriter(url string) *LokiWriter {
+	return &LokiWriter{url: url, stopped: make(chan struct{})}
+}
+
+// WriteLog adds the log to the current batch (b is used as its line), queueing the batch if it is full.
+func (lw *LokiWriter) WriteLog(l *Log, b []byte) error {
+	lw.startOnce.Do(lw.start)
+
+	labels := lw.labels(l)
+	ts := time.Now()
+	if t, ok := l.Data[DataKeyTimestamp].(time.Time); ok {
+		ts = t
+	}
+	line := strings.TrimSuffix(string(b), "\n")
+
+	lw.mu.Lock()
+	if lw.closed {
+		lw.mu.Unlock()
+		return os.ErrClosed
+	}
+	if lw.batch == nil {
+		lw.batch = &lokiBatch{streams: map[string]*lokiStream{}}
+	}
+	key := lokiLabelsKey(labels)
+	stream, ok := lw.batch.streams[key]
+	if !ok {
+		stream = &lokiStream{Stream: labels}
+		lw.batch.streams[key] = stream
+	}
+	stream.Values = append(stream.Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), line})
+	lw.batch.size += len(line)
+	var full *lokiBatch
+	if lw.batch.size >= lw.batchSize() {
+		full, lw.batch = lw.batch, nil
+		lw.inflight.Add(1)
+	}
+	lw.mu.Unlock()
+
+	if full != nil {
+		defer lw.inflight.Done()
+		lw.queue <- lokiQueued{batch: full}
+	}
+	return nil
+}
+
+// Write decodes a log serialized as JSON (see NewReader) and adds it to the current batch.
+func (lw *LokiWriter) Write(b []byte) (int, error) {
+	l, err := NewReader(bytes.NewReader(b)).Next()
+	if err != nil {
+		return 0, fmt.Errorf("decode log: %w", err)
+	}
+	if err := lw.WriteLog(l, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush sends the current batch and waits until all queued batches are sent.
+func (lw *LokiWriter) Flush() error {
+	lw.startOnce.Do(lw.start)
+
+	lw.mu.Lock()
+	if lw.closed {
+		lw.mu.Unlock()
+		return nil
+	}
+	batch := lw.batch
+	lw.batch = nil
+	lw.inflight.Add(1)
+	lw.mu.Unlock()
+
+	defer lw.inflight.Done()
+	done := make(chan error, 1)
+	lw.queue <- lokiQueued{batch: batch, done: done}
+	return <-done
+}
+
+// Close sends the current batch, waits until all queued batches are sent and stops the background goroutine.
+func (lw *LokiWriter) Close() error {
+	lw.startOnce.Do(lw.start)
+
+	lw.mu.Lock()
+	if lw.closed {
+		lw.mu.Unlock()
+		return nil
+	}
+	lw.closed = true
+	batch := lw.batch
+	lw.batch = nil
+	lw.mu.Unlock()
+
+	lw.inflight.Wait()
+	done := make(chan error, 1)
+	lw.queue <- lokiQueued{batch: batch, done: done}
+	close(lw.queue)
+	err := <-done
+	<-lw.stopped
+	return err
+}
+
+// start creates the queue and starts the background goroutine sending batches.
+func (lw *LokiWriter) start() {
+	size := lw.QueueSize
+	if size <= 0 {
+		size = 4
+	}
+	interval := lw.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	lw.queue = make(chan lokiQueued, size)
+
+	go func() {
+		defer close(lw.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case queued, ok := <-lw.queue:
+				if !ok {
+					return
+				}
+				err := lw.send(queued.batch)
+				if queued.done != nil {
+					queued.done <- err
+				} else if err != nil {
+					lw.report(err)
+				}
+			case <-ticker.C:
+				lw.mu.Lock()
+				batch := lw.batch
+				lw.batch = nil
+				lw.mu.Unlock()
+				if err := lw.send(batch); err != nil {
+					lw.report(err)
+				}
+			}
+		}
+	}()
+}
+
+// send pushes a batch, retrying with an exponential backoff on network errors, rate limiting and server errors.
+func (lw *LokiWriter) send(batch *lokiBatch) error {
+	if batch == nil || len(batch.streams) == 0 {
+		return nil
+	}
+	streams := make([]*lokiStream, 0, len(batch.streams))
+	for _, stream := range batch.streams {
+		streams = append(streams, stream)
+	}
+	payload, err := json.Marshal(map[string]any{"streams": streams})
+	if err != nil {
+		return err
+	}
+
+	retries := lw.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		temporary, err := lw.post(payload)
+		if err == nil || !temporary || attempt >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// post sends a push request, it reports whether the error (if any) is temporary.
+func (lw *LokiWriter) post(payload []byte) (temporary bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, lw.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	for k, values := range lw.Header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if lw.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", lw.TenantID)
+	}
+
+	client := lw.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("push logs to loki: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		temporary = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return temporary, fmt.Errorf("push logs to loki: unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return false, nil
+}
+
+// labels returns the labels of the stream of a log.
+func (lw *LokiWriter) labels(l *Log) map[string]string {
+	keys := lw.LabelKeys
+	if keys == nil {
+		keys = []string{DataKeyLevel}
+	}
+	labels := make(map[string]string, len(lw.Labels)+len(keys))
+	for k, v := range lw.Labels {
+		labels[k] = v
+	}
+	for _, k := range keys {
+		v, ok := l.Data[k]
+		if !ok {
+			continue
+		}
+		name := lokiInvalidLabelChars.ReplaceAllString(strings.TrimPrefix(k, dataKeyPrefix), "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		labels[name] = fmt.Sprint(v)
+	}
+	return labels
+}
+
+// lokiLabelsKey returns a key identifying a set of labels.
+func lokiLabelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[name]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// report passes an error of the background goroutine to OnError (if set).
+func (lw *LokiWriter) report(err error) {
+	if lw.OnError != nil {
+		lw.OnError(err)
+	}
+}
+
+// batchSize returns the configured batch size or the default one.
+func (lw *LokiWriter) batchSize() int {
+	if lw.BatchSize <= 0 {
+		return 1 << 20
+	}
+	return lw.BatchSize
+}
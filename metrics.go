@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"expvar"
+	"io"
+)
+
+// Metrics is a hook counting logs, write errors and dropped logs, exported as expvar variables
+// (served as JSON on /debug/vars by the expvar package) so operators can alert on error rates.
+// Byte counts are recorded for the writers wrapped with Writer.
+//
+// For ex:
+//
+//	metrics := logs.NewMetrics("logs")
+//	logger := &logs.DefaultLogger{
+//		Writers: []io.Writer{metrics.Writer("stdout", os.Stdout)},
+//		Hooks:   []logs.Hook{metrics},
+//	}
+type Metrics struct {
+	Vars        *expvar.Map // Holds the variables below
+	Logs        *expvar.Map // Number of logs per level ("NONE" for logs without a level)
+	Bytes       *expvar.Map // Number of bytes written per writer name
+	WriteErrors *expvar.Int // Number of serialization and write errors
+	Drops       *expvar.Map // Number of dropped logs per reason (see DropReasonLevel and others)
+}
+
+// NewMetrics returns new metrics published under the given expvar name
+// (not published if the name is empty, for ex: to publish them later on).
+// Like expvar.Publish, it panics if the name is already in use.
+func NewMetrics(name string) *Metrics {
+	m := &Metrics{
+		Vars:        new(expvar.Map).Init(),
+		Logs:        new(expvar.Map).Init(),
+		Bytes:       new(expvar.Map).Init(),
+		WriteErrors: new(expvar.Int),
+		Drops:       new(expvar.Map).Init(),
+	}
+	m.Vars.Set("logs", m.Logs)
+	m.Vars.Set("bytes", m.Bytes)
+	m.Vars.Set("write_errors", m.WriteErrors)
+	m.Vars.Set("dropped", m.Drops)
+	if name != "" {
+		expvar.Publish(name, m.Vars)
+	}
+	return m
+}
+
+// BeforeSerialize counts the log by level.
+func (m *Metrics) BeforeSerialize(l *Log) {
+	label := "NONE"
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok {
+		label = lvl.String()
+	}
+	m.Logs.Add(label, 1)
+}
+
+// AfterWrite counts write errors.
+func (m *Metrics) AfterWrite(l *Log, b []byte, err error) {
+	if err != nil {
+		m.WriteErrors.Add(1)
+	}
+}
+
+// Dropped counts the dropped log by reason.
+func (m *Metrics) Dropped(l *Log, reason string) { m.Drops.Add(reason, 1) }
+
+// Writer returns a writer counting the bytes written to w under the given name.
+// The logger flushes, syncs and closes w itself (rather than the returned writer).
+func (m *Metrics) Writer(name string, w io.Writer) io.Writer {
+	return &countingWriter{w: w, name: name, bytes: m.Bytes}
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w     io.Writer
+	name  string
+	bytes *expvar.Map
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.bytes.Add(cw.name, int64(n))
+	return n, err
+}
+
+// WriteLog writes a serialized log to the underlying writer (see LogWriter).
+func (cw *countingWriter) WriteLog(l *Log, b []byte) error {
+	n, err := writeLog(cw.w, l, b)
+	cw.bytes.Add(cw.name, int64(n))
+	return err
+}
+
+// Unwrap returns the underlying writer.
+func (cw *countingWriter) Unwrap() io.Writer { return cw.w }
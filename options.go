@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithData adds more data to a log.
+// The value should serializable in order to be writable to the logger output.
+// Values implementing Loggable are stored as the data they return.
+func WithData(key string, value any) LogOption {
+	return func(l *Log) { l.set(key, value) }
+}
+
+// WithGroup applies the given options to a group of data nested in the log under the given name (as a map),
+// like slog groups, so that their keys don't collide with the other keys of the log,
+// for ex: WithGroup("http", WithData("status", 200)) adds {"http": {"status": 200}}.
+// Groups with the same name are merged (a value that is not a group is replaced), groups can be nested.
+// Nothing is added if the options add no data.
+func WithGroup(name string, opts ...LogOption) LogOption {
+	return func(l *Log) {
+		group := map[string]any{}
+		if existing, ok := l.Data[name].(map[string]any); ok {
+			for k, v := range existing {
+				group[k] = v // Copied so that a map shared with other logs is not modified
+			}
+		}
+		sub := &Log{Message: l.Message, Data: group, callerSkip: l.callerSkip, clock: l.clock}
+		for _, opt := range opts {
+			opt(sub)
+		}
+		if len(group) > 0 {
+			l.Data[name] = group
+		}
+	}
+}
+
+// Typed versions of WithData, they make the type of a field explicit at the call site.
+// They don't avoid boxing: values are stored in the data map as interface values (which usually allocates)
+// and serialized like with WithData. Logs are written without boxing nor allocating with Field values instead
+// (see DefaultLogger.LogFields).
+
+// WithString adds a string to the log.
+func WithString(key string, value string) LogOption { return func(l *Log) { l.set(key, value) } }
+
+// WithInt64 adds an integer to the log.
+func WithInt64(key string, value int64) LogOption { return func(l *Log) { l.set(key, value) } }
+
+// WithFloat64 adds a floating point number to the log.
+func WithFloat64(key string, value float64) LogOption { return func(l *Log) { l.set(key, value) } }
+
+// WithBool adds a boolean to the log.
+func WithBool(key string, value bool) LogOption { return func(l *Log) { l.set(key, value) } }
+
+// WithDuration adds a duration to the log (serialized as a number of nanoseconds in JSON).
+func WithDuration(key string, value time.Duration) LogOption {
+	return func(l *Log) { l.set(key, value) }
+}
+
+// WithTime adds a date and time to the log (serialized in the RFC 3339 format in JSON).
+func WithTime(key string, value time.Time) LogOption { return func(l *Log) { l.set(key, value) } }
+
+// WithTimestamp adds a creation datetime to the log.
+// The time is read from the clock of the logger when used as a base option (see DefaultLogger.Clock).
+func WithTimestamp() LogOption { return func(l *Log) { l.Data[DataKeyTimestamp] = l.now() } }
+
+// WithLevel adds a severity level to the log.
+func WithLevel(lvl string) LogOption { return func(l *Log) { l.Data[DataKeyLevel] = lvl } }
+
+// WithSrc stores the location where the log was created in the source code.
+// Frames of this package (for ex: Logger methods or With) are skipped, so the reported location is the call site
+// of the logging function, even when WithSrc is used as a base option of a DefaultLogger (see DefaultLogger.CallerSkip).
+func WithSrc() LogOption { return WithSrcSkip(0) }
+
+// WithSrcSkip is like WithSrc but additionally skips the given number of frames,
+// so that wrapper helpers can report the location where they are called.
+func WithSrcSkip(skip int) LogOption {
+	return func(l *Log) {
+		frame, ok := callerFrame(skip + l.callerSkip)
+		if !ok {
+			return
+		}
+		l.Data[DataKeySrcFunction] = frame.Function
+		l.Data[DataKeySrcFileLine] = frame.File + ":" + strconv.Itoa(frame.Line)
+	}
+}
+
+// Prefix of the names of the functions of this package (excluding subpackages).
+const packageFuncPrefix = "github.com/ejuju/go-logs."
+
+// callerFrame returns the first frame outside of this package, after skipping the given number of frames.
+func callerFrame(skip int) (runtime.Frame, bool) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packageFuncPrefix) {
+			if skip <= 0 {
+				return frame, true
+			}
+			skip--
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// WithFS adds info about a file system (name and size of files) to the log.
+// If the file system cannot be walked, the error message is stored instead.
+func WithFSys(fsys fs.FS) LogOption { return WithFSLimited(DataKeyFSys, fsys, 0) }
+
+// WithFSLimited adds info about a file system (name and size of files) to the log under the given key.
+// At most maxFiles files are listed (no limit if maxFiles <= 0),
+// this avoids walking huge directories synchronously when creating the log.
+// If the file system cannot be walked, the error message is stored instead.
+func WithFSLimited(key string, fsys fs.FS, maxFiles int) LogOption {
+	type FileInfo struct {
+		Path string `json:"path"`
+		Size int    `json:"size"`
+	}
+
+	return func(l *Log) {
+		files := []FileInfo{}
+		err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if maxFiles > 0 && len(files) >= maxFiles {
+				return errMaxFilesReached
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, FileInfo{
+				Path: path,
+				Size: int(info.Size()),
+			})
+			return nil
+		})
+		if err != nil && err != errMaxFilesReached {
+			l.set(key, err.Error())
+			return
+		}
+		l.set(key, files)
+	}
+}
+
+// errMaxFilesReached is used to stop walking a file system once enough files have been listed.
+var errMaxFilesReached = errors.New("max number of files reached")
@@ -0,0 +1,35 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// brokenFS fails to open any file.
+type brokenFS struct{}
+
+func (brokenFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("disk on fire")}
+}
+
+func TestWithFSReportsWalkErrors(t *testing.T) {
+	l := NewLog("msg", WithFSys(brokenFS{}))
+	if got, want := l.Data[DataKeyFSys], "open .: disk on fire"; got != want {
+		t.Fatalf("got %#v, want the walk error %q", got, want)
+	}
+}
+
+func TestWithFSLimited(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}, "b.txt": {Data: []byte("bb")}, "dir/c.txt": {Data: []byte("ccc")}}
+	l := NewLog("msg", WithFSLimited("files", fsys, 2))
+	files, err := json.Marshal(l.Data["files"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(files), `[{"path":"a.txt","size":1},{"path":"b.txt","size":2}]`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
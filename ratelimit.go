@@ -0,0 +1,84 @@
+package logs
+
+import "time"
+
+// DataKeySuppressed holds the number of logs suppressed by rate limiting in rate limit summaries.
+const DataKeySuppressed = dataKeyPrefix + "suppressed"
+
+// RateLimit limits the number of logs of a level written per second using a token bucket,
+// this protects outputs against log storms.
+//
+// When logs have been suppressed, a summary log with their count (at the same level)
+// is written before the next log that is allowed through once the burst has subsided.
+type RateLimit struct {
+	PerSecond float64 // For ex: 100 logs written per second on average
+	Burst     int     // For ex: 200 logs written at once at most (defaults to PerSecond rounded up, and at least 1)
+}
+
+// rateLimiter holds the state of the rate limiting of a logger.
+type rateLimiter struct {
+	buckets map[LogLevel]*tokenBucket
+}
+
+// tokenBucket holds the state of the rate limiting of a level.
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+}
+
+// allow reports whether a log should be written, refilling the bucket of its level first.
+// If logs were suppressed before it, a summary log reporting them is returned too.
+func (rl *rateLimiter) allow(limits map[LogLevel]RateLimit, l *Log) (ok bool, summary *Log) {
+	lvl, hasLevel := levelOf(l.Data[DataKeyLevel])
+	limit, limited := limits[lvl]
+	if !hasLevel || !limited {
+		return true, nil
+	}
+
+	// Take token, refilling bucket first
+	if rl.buckets == nil {
+		rl.buckets = map[LogLevel]*tokenBucket{}
+	}
+	b, found := rl.buckets[lvl]
+	if !found {
+		b = &tokenBucket{}
+		rl.buckets[lvl] = b
+	}
+	if !b.take(limit, time.Now()) {
+		b.suppressed++
+		return false, nil
+	}
+	if b.suppressed > 0 {
+		summary = NewLog("rate limit suppressed logs", WithLevel(lvl.String()), WithData(DataKeySuppressed, b.suppressed))
+		b.suppressed = 0
+	}
+	return true, summary
+}
+
+// take refills the bucket according to the limit and takes a token, it returns false if there is none left.
+// A new bucket starts full.
+func (b *tokenBucket) take(limit RateLimit, now time.Time) bool {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = limit.PerSecond
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * limit.PerSecond
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
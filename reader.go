@@ -0,0 +1,143 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Reader reads logs written as JSON (for ex: by AsJSON, AsCompactJSON, AsPrettyJSON or AsOrderedJSON),
+// whether they are separated by line breaks, commas or surrounded by prefixes.
+// Anything outside of top-level JSON objects is skipped, so prefixes must not contain "{".
+//
+// Timestamps stored under DataKeyTimestamp are parsed back to time.Time.
+// Logs written as CBOR can be read with NewCBORReader instead.
+type Reader struct {
+	r    *bufio.Reader
+	cbor bool // Read logs written as CBOR instead of JSON (see NewCBORReader)
+}
+
+// NewReader returns a reader reading logs from r.
+func NewReader(r io.Reader) *Reader { return &Reader{r: bufio.NewReader(r)} }
+
+// Next returns the next log, or io.EOF when there are no more logs.
+func (lr *Reader) Next() (*Log, error) {
+	if lr.cbor {
+		return lr.nextCBORLog()
+	}
+	b, err := lr.nextObject()
+	if err != nil {
+		return nil, err
+	}
+	l, err := decodeJSONLog(b)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := l.Data[DataKeyTimestamp].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			l.Data[DataKeyTimestamp] = t
+		}
+	}
+	return l, nil
+}
+
+// decodeJSONLog decodes a log written as a JSON object, either with the nested "data" object of AsJSON
+// or with flat data keys (as written by AsOrderedJSON).
+func decodeJSONLog(b []byte) (*Log, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	_, nested := fields["data"]
+	for k := range fields {
+		nested = nested && (k == "message" || k == "data")
+	}
+	if nested || len(fields) <= 1 {
+		l := &Log{}
+		if err := json.Unmarshal(b, l); err != nil {
+			return nil, err
+		}
+		if l.Data == nil {
+			l.Data = map[string]any{}
+		}
+		return l, nil
+	}
+
+	l := &Log{Data: map[string]any{}}
+	for k, raw := range fields {
+		var err error
+		switch k {
+		case "message":
+			err = json.Unmarshal(raw, &l.Message)
+		case "data.message":
+			var v any
+			err = json.Unmarshal(raw, &v)
+			l.Data["message"] = v
+		default:
+			var v any
+			err = json.Unmarshal(raw, &v)
+			l.Data[k] = v
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// ReadAll returns all the remaining logs.
+func (lr *Reader) ReadAll() ([]*Log, error) {
+	var logs []*Log
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return logs, nil
+		} else if err != nil {
+			return logs, err
+		}
+		logs = append(logs, l)
+	}
+}
+
+// nextObject returns the bytes of the next top-level JSON object.
+func (lr *Reader) nextObject() ([]byte, error) {
+	// Skip separators and prefixes
+	for {
+		c, err := lr.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == '{' {
+			break
+		}
+	}
+
+	// Read until the matching closing brace
+	buf := bytes.NewBuffer([]byte{'{'})
+	depth, inString, escaped := 1, false, false
+	for depth > 0 {
+		c, err := lr.r.ReadByte()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(c)
+
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && c == '{':
+			depth++
+		case !inString && c == '}':
+			depth--
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const (
+	DataKeyPanic        = dataKeyPrefix + "panic"         // Value of recovered panics
+	DataKeyPanicType    = dataKeyPrefix + "panic_type"    // Go type of the value of recovered panics
+	DataKeyRuntimePanic = dataKeyPrefix + "runtime_panic" // Whether a recovered panic is a runtime error (for ex: a nil pointer dereference)
+)
+
+// WithRecovered adds a recovered panic to the log: its value and type, whether it is a runtime error
+// (for ex: an index out of range), the error and the errors it wraps if the value is an error (see WithError),
+// the ID of the goroutine (see WithGoroutineID) and the stack trace starting at the panicking function.
+// It must be created in the deferred function that recovered the panic, for ex:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			log(logs.NewLog("recovered from panic", logs.WithLevel(logs.LevelPanic.String()), logs.WithRecovered(r)))
+//		}
+//	}()
+func WithRecovered(value any) LogOption {
+	stack := panicStack(callerStack(1))
+	id, hasID := goroutineID()
+	return func(l *Log) {
+		l.Data[DataKeyPanic] = fmt.Sprint(value)
+		l.Data[DataKeyPanicType] = fmt.Sprintf("%T", value)
+		if err, ok := value.(error); ok {
+			var re runtime.Error
+			l.Data[DataKeyRuntimePanic] = errors.As(err, &re)
+			WithError(err)(l)
+		} else {
+			l.Data[DataKeyRuntimePanic] = false
+		}
+		if hasID {
+			l.Data[DataKeyGoroutineID] = id
+		}
+		l.Data[DataKeyStack] = stack
+	}
+}
+
+// panicStack returns the part of a stack trace (see callerStack) of a deferred function starting at the panicking function,
+// skipping the deferred function and the frames of the runtime raising the panic.
+// The whole stack is returned if the deferred function is not running because of a panic.
+func panicStack(stack []string) []string {
+	for i, frame := range stack {
+		if !strings.HasPrefix(frame, "runtime.gopanic ") {
+			continue
+		}
+		i++
+		for i < len(stack)-1 && strings.HasPrefix(stack[i], "runtime.") {
+			i++
+		}
+		return stack[i:]
+	}
+	return stack
+}
+
+// RecoverOption configures RecoverAndLog.
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	repanic bool
+	errp    *error
+}
+
+// Repanic makes RecoverAndLog panic again with the recovered value once it has been logged.
+func Repanic() RecoverOption { return func(c *recoverConfig) { c.repanic = true } }
+
+// RecoverInto makes RecoverAndLog store the recovered panic as an error in errp,
+// typically the named error result of the function deferring RecoverAndLog.
+func RecoverInto(errp *error) RecoverOption { return func(c *recoverConfig) { c.errp = errp } }
+
+// RecoverAndLog recovers from a panic and writes a log at panic level
+// with the panic value and the stack trace of the panicking goroutine (see WithRecovered).
+// It must be deferred directly, for ex: defer logs.RecoverAndLog(log).
+func RecoverAndLog(log LoggerFunc, opts ...RecoverOption) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	c := &recoverConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	log(NewLog("recovered from panic", WithLevel(LevelPanic.String()), WithRecovered(r)))
+
+	if c.errp != nil {
+		if err, ok := r.(error); ok {
+			*c.errp = fmt.Errorf("panic: %w", err)
+		} else {
+			*c.errp = fmt.Errorf("panic: %v", r)
+		}
+	}
+	if c.repanic {
+		panic(r)
+	}
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redactor masks sensitive data in a log before it is serialized (for ex: to comply with GDPR or PCI DSS).
+type Redactor interface {
+	Redact(l *Log)
+}
+
+// RedactorFunc implements Redactor with a function.
+type RedactorFunc func(l *Log)
+
+// Redact calls the function.
+func (fn RedactorFunc) Redact(l *Log) { fn(l) }
+
+// Common patterns of sensitive string values, for use in FieldRedactor.Patterns.
+var (
+	PatternEmail      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	PatternCreditCard = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// FieldRedactor masks the values of the given data keys
+// and the parts of the message and string values matching the given patterns.
+// Nested maps (map[string]any) and slices ([]any) are redacted too, without modifying the original ones.
+type FieldRedactor struct {
+	Keys     []string         // For ex: "password" or "authorization" (case-insensitive)
+	Patterns []*regexp.Regexp // For ex: PatternEmail
+	Mask     string           // Defaults to "[REDACTED]"
+}
+
+// Redact masks the sensitive data of the log.
+func (fr *FieldRedactor) Redact(l *Log) {
+	l.Message = fr.redactString(l.Message)
+	for k, v := range l.Data {
+		l.Data[k] = fr.redactValue(k, v)
+	}
+}
+
+// redactValue returns the redacted version of a data value stored under the given key.
+func (fr *FieldRedactor) redactValue(key string, v any) any {
+	for _, k := range fr.Keys {
+		if strings.EqualFold(k, key) {
+			return fr.mask()
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return fr.redactString(v)
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for k, elem := range v {
+			redacted[k] = fr.redactValue(k, elem)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, elem := range v {
+			redacted[i] = fr.redactValue("", elem)
+		}
+		return redacted
+	}
+	return v
+}
+
+// redactString masks the parts of a string matching the patterns.
+func (fr *FieldRedactor) redactString(s string) string {
+	for _, p := range fr.Patterns {
+		s = p.ReplaceAllLiteralString(s, fr.mask())
+	}
+	return s
+}
+
+// mask returns the string standing in for redacted values.
+func (fr *FieldRedactor) mask() string {
+	if fr.Mask == "" {
+		return redactedValue
+	}
+	return fr.Mask
+}
@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry provides named loggers (one per component of an application, for ex: "http" or "db")
+// whose minimum level can be changed at runtime, per name.
+//
+// Names are hierarchical: the level of "http.client" defaults to the level of "http",
+// which defaults to the level set for the empty name (the default level, LevelUnknown unless set).
+// Logs without a level are always written.
+//
+// The loggers write to the parent logger func given to NewRegistry:
+// its own minimum level also applies, so it should be left unset when using the registry to tune levels.
+type Registry struct {
+	parent  LoggerFunc
+	mu      sync.RWMutex
+	levels  map[string]LogLevel // Overrides, by name
+	loggers map[string]LoggerFunc
+}
+
+// NewRegistry returns a registry of loggers writing to parent (for ex: the method value dl.Log of a DefaultLogger).
+func NewRegistry(parent LoggerFunc) *Registry {
+	return &Registry{parent: parent, levels: map[string]LogLevel{}, loggers: map[string]LoggerFunc{}}
+}
+
+// Get returns the logger with the given name, creating it on first use.
+// Its logs are tagged with the name (see LoggerFunc.Named) and dropped when their level is below the level of the name.
+// Loggers derived from it with Named are also named loggers of the registry (for ex: Get("http").Named("client")).
+func (r *Registry) Get(name string) LoggerFunc {
+	r.mu.RLock()
+	fn, ok := r.loggers[name]
+	r.mu.RUnlock()
+	if ok {
+		return fn
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fn, ok := r.loggers[name]; ok {
+		return fn
+	}
+	fn = LoggerFunc(r.log).Named(name)
+	r.loggers[name] = fn
+	return fn
+}
+
+// log writes a log with the parent logger func, unless its level is below the level of its component.
+func (r *Registry) log(l *Log) error {
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok {
+		name, _ := l.Data[DataKeyComponent].(string)
+		if lvl < r.Level(name) {
+			return nil
+		}
+	}
+	return r.parent(l)
+}
+
+// SetLevel sets the minimum level of the loggers with the given name and of their descendants
+// (unless they have their own level). The empty name sets the default level.
+// It is safe to call concurrently with logging, the change takes effect immediately.
+func (r *Registry) SetLevel(name string, lvl LogLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels[name] = lvl
+}
+
+// ResetLevel removes the level set for the given name, so that it inherits the level of its parent name again.
+func (r *Registry) ResetLevel(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.levels, name)
+}
+
+// Level returns the minimum level of the loggers with the given name:
+// the level set for the name or for its closest parent name, or the default level.
+func (r *Registry) Level(name string) LogLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for {
+		if lvl, ok := r.levels[name]; ok {
+			return lvl
+		}
+		if name == "" {
+			return LevelUnknown
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			i = 0
+		}
+		name = name[:i]
+	}
+}
+
+// Levels returns the levels set with SetLevel, by name.
+func (r *Registry) Levels() map[string]LogLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	levels := make(map[string]LogLevel, len(r.levels))
+	for name, lvl := range r.levels {
+		levels[name] = lvl
+	}
+	return levels
+}
+
+// Names returns the names of the loggers created with Get, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.loggers))
+	for name := range r.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handler returns an HTTP handler to read and change the levels of the registry at runtime (like LevelHandler).
+//
+// GET requests return the levels set, by name, as JSON (for ex: {"":"INFO","db":"DEBUG"}).
+// PUT requests set the levels of a JSON body with the same format (a null level resets the level of a name, see ResetLevel),
+// other names are left untouched. They return the new levels.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			b, err := io.ReadAll(io.LimitReader(req.Body, 64*1024))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var body map[string]*LogLevel
+			if err := json.Unmarshal(b, &body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for name, lvl := range body {
+				if lvl == nil {
+					r.ResetLevel(name)
+				} else {
+					r.SetLevel(name, *lvl)
+				}
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(r.Levels())
+	})
+}
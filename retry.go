@@ -0,0 +1,65 @@
+package logs
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrSerialize is wrapped by the errors returned when a log cannot be serialized,
+// so that they can be told apart from write errors (with errors.Is).
+// Serialization is deterministic: such errors are never retried.
+var ErrSerialize = errors.New("serialize log")
+
+// RetryPolicy retries the writes failing with a transient error (see IsTransient), with an exponential backoff.
+// A write is only retried if no byte has been written, so that a log is never partially written twice.
+//
+// Retries happen while the logger lock is held: in sync mode, they block the callers writing logs
+// (use Async to keep callers unaffected).
+type RetryPolicy struct {
+	MaxRetries int              // For ex: 5 attempts after the first one (3 by default)
+	MinBackoff time.Duration    // For ex: 10ms before the first retry (1ms by default), doubled after each retry
+	MaxBackoff time.Duration    // For ex: 1s between retries at most (100ms by default)
+	Transient  func(error) bool // For ex: also retry the errors of a custom writer (IsTransient by default)
+}
+
+// IsTransient reports whether a write error is likely to go away if the write is retried:
+// errors with a Timeout or Temporary method returning true (for ex: network timeouts and interrupted system calls).
+func IsTransient(err error) bool {
+	var te interface{ Timeout() bool }
+	if errors.As(err, &te) && te.Timeout() {
+		return true
+	}
+	var tmp interface{ Temporary() bool }
+	return errors.As(err, &tmp) && tmp.Temporary()
+}
+
+// writeLog writes a serialized log to w (see writeLog), retrying transient failures.
+// A nil policy writes once.
+func (p *RetryPolicy) writeLog(w io.Writer, l *Log, b []byte) (int, error) {
+	n, err := writeLog(w, l, b)
+	if p == nil {
+		return n, err
+	}
+	retries, backoff, maxBackoff, transient := p.MaxRetries, p.MinBackoff, p.MaxBackoff, p.Transient
+	if retries <= 0 {
+		retries = 3
+	}
+	if backoff <= 0 {
+		backoff = time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 100 * time.Millisecond
+	}
+	if transient == nil {
+		transient = IsTransient
+	}
+	for attempt := 0; err != nil && n == 0 && attempt < retries && transient(err); attempt++ {
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		n, err = writeLog(w, l, b)
+	}
+	return n, err
+}
@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Route sends the logs matching its predicate to a destination (see Router).
+type Route struct {
+	Match func(*Log) bool // For ex: MatchLevel(LevelError) (all logs match when nil)
+	To    LoggerFunc      // For ex: the Log method of a DefaultLogger writing to a file
+	Final bool            // For ex: true to stop routing the logs matched by this route to the following routes
+}
+
+// Router returns a logger func sending each log to the destinations of the routes it matches, in order
+// (a log matching no route is dropped), for ex:
+//
+//	log := logs.Router(
+//		logs.Route{Match: logs.MatchLevel(logs.LevelError), To: errorFile.Log},
+//		logs.Route{Match: logs.MatchLevel(logs.LevelError), To: alerts.Log},
+//		logs.Route{To: stdout.Log},
+//	)
+//
+// Unlike the writers of a DefaultLogger, each destination has its own configuration
+// (serializer, base options, filters, etc.). Destinations receive a copy of the log,
+// so they can modify it (for ex: to redact data) without affecting the other destinations.
+// Errors are aggregated and returned once the log has been sent to all destinations.
+func Router(routes ...Route) LoggerFunc {
+	return func(l *Log) error {
+		var errs errWrapper
+		for _, route := range routes {
+			if route.Match != nil && !route.Match(l) {
+				continue
+			}
+			if err := route.To(l.clone()); err != nil {
+				errs = append(errs, err)
+			}
+			if route.Final {
+				break
+			}
+		}
+		if errs != nil {
+			return errs
+		}
+		return nil
+	}
+}
+
+// MatchLevel returns a predicate matching the logs at or above the given level (logs without a level don't match).
+func MatchLevel(min LogLevel) func(*Log) bool {
+	return func(l *Log) bool {
+		lvl, ok := levelOf(l.Data[DataKeyLevel])
+		return ok && lvl >= min
+	}
+}
+
+// MatchComponent returns a predicate matching the logs of the given component and its subcomponents
+// (for ex: "http" matches "http" and "http.client", see LoggerFunc.Named).
+func MatchComponent(name string) func(*Log) bool {
+	return func(l *Log) bool {
+		component, _ := l.Data[DataKeyComponent].(string)
+		return component == name || strings.HasPrefix(component, name+".")
+	}
+}
+
+// MatchData returns a predicate matching the logs holding the given data value (compared with reflect.DeepEqual).
+func MatchData(key string, value any) func(*Log) bool {
+	return func(l *Log) bool {
+		v, ok := l.Data[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// MatchAll returns a predicate matching the logs matched by all the given predicates.
+func MatchAll(preds ...func(*Log) bool) func(*Log) bool {
+	return func(l *Log) bool {
+		for _, pred := range preds {
+			if !pred(l) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAny returns a predicate matching the logs matched by any of the given predicates.
+func MatchAny(preds ...func(*Log) bool) func(*Log) bool {
+	return func(l *Log) bool {
+		for _, pred := range preds {
+			if pred(l) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const (
+	DataKeyRuntime     = dataKeyPrefix + "runtime"
+	DataKeyGoroutineID = dataKeyPrefix + "goroutine_id"
+)
+
+// WithRuntimeStats adds statistics about the Go runtime to the log (under DataKeyRuntime):
+// the number of goroutines, the heap memory in use (in bytes), the number of garbage collections,
+// the total time spent in GC pauses and the duration of the last pause (in nanoseconds in JSON).
+//
+// Reading memory statistics briefly stops the program (see runtime.ReadMemStats),
+// so this option is meant for periodic or exceptional logs rather than as a base option.
+func WithRuntimeStats() LogOption {
+	return func(l *Log) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		stats := map[string]any{
+			"goroutines":     runtime.NumGoroutine(),
+			"heap_in_use":    ms.HeapInuse,
+			"num_gc":         ms.NumGC,
+			"gc_pause_total": time.Duration(ms.PauseTotalNs),
+			"gc_pause_last":  time.Duration(0),
+		}
+		if ms.NumGC > 0 {
+			stats["gc_pause_last"] = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+		}
+		l.Data[DataKeyRuntime] = stats
+	}
+}
+
+// WithGoroutineID adds the ID of the goroutine creating the log (under DataKeyGoroutineID),
+// for ex: to tell apart the logs of concurrent workers when debugging.
+// Go doesn't expose goroutine IDs, so it is read from the header of the goroutine stack trace:
+// IDs are only meant for debugging and may be reused once a goroutine exits.
+func WithGoroutineID() LogOption {
+	return func(l *Log) {
+		if id, ok := goroutineID(); ok {
+			l.Data[DataKeyGoroutineID] = id
+		}
+	}
+}
+
+// goroutineID returns the ID of the current goroutine,
+// parsed from the first line of its stack trace (for ex: "goroutine 18 [running]:").
+func goroutineID() (uint64, bool) {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	return id, err == nil
+}
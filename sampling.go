@@ -0,0 +1,126 @@
+package logs
+
+import (
+	"strconv"
+	"time"
+)
+
+// DataKeyDropped holds the number of logs dropped by sampling in sampling summaries.
+const DataKeyDropped = dataKeyPrefix + "dropped"
+
+// DataKeyOccurrence holds the number of logs created at the call site of a log sampled per call site.
+const DataKeyOccurrence = dataKeyPrefix + "occurrence"
+
+// SampleRate limits the number of identical logs (same level and message) written per time window.
+// In each window, the first Initial identical logs are written, then only one out of Thereafter.
+//
+// When logs have been dropped during a window, a summary log with their count
+// is written before the first log of a following window.
+type SampleRate struct {
+	Initial    int           // For ex: 100 identical logs written per window before sampling starts
+	Thereafter int           // For ex: 10 to write 1 out of 10 identical logs afterwards (0 drops all of them)
+	Tick       time.Duration // For ex: 1s per window (the default)
+}
+
+// sampler holds the state of the sampling of a logger.
+type sampler struct {
+	windowEnd time.Time
+	counts    map[string]int
+	dropped   int
+}
+
+// summary starts a new window if the current one is over,
+// returning a log reporting the logs dropped during the previous window (if any).
+func (s *sampler) summary(rate *SampleRate) *Log {
+	now := time.Now()
+	if now.Before(s.windowEnd) {
+		return nil
+	}
+	tick := rate.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	s.windowEnd, s.counts = now.Add(tick), map[string]int{}
+
+	if s.dropped == 0 {
+		return nil
+	}
+	summary := NewLog("sampling dropped logs", WithLevel(LevelWarn.String()), WithData(DataKeyDropped, s.dropped))
+	s.dropped = 0
+	return summary
+}
+
+// allow reports whether a log should be written.
+func (s *sampler) allow(rate *SampleRate, l *Log) bool {
+	key := identityKey(l)
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= rate.Initial || (rate.Thereafter > 0 && (n-rate.Initial)%rate.Thereafter == 0) {
+		return true
+	}
+	s.dropped++
+	return false
+}
+
+// CallSiteSampling samples noisy logs per call site (source file and line) with an exponential rate:
+// the 1st, 10th, 100th, 1000th... logs created at each call site are written, for ex: for debug logs in tight loops.
+// Written logs hold their occurrence number (under DataKeyOccurrence) and the number of logs dropped
+// since the previous one written at the same call site (under DataKeyDropped).
+type CallSiteSampling struct {
+	MaxLevel LogLevel // For ex: LevelTrace to only sample trace logs (LevelDebug by default), logs without a level are not sampled
+	Base     int      // For ex: 2 to write the 1st, 2nd, 4th, 8th... logs (10 by default)
+}
+
+// callSiteState holds the state of the sampling of a call site.
+type callSiteState struct {
+	count uint64 // Logs created at the call site
+	next  uint64 // Occurrence number of the next log to write
+	last  uint64 // Occurrence number of the last log written
+}
+
+// callSiteSampler holds the state of the per call site sampling of a logger.
+type callSiteSampler struct {
+	sites map[string]*callSiteState // By source file and line
+}
+
+// allow reports whether a log should be written, adding its occurrence number if so.
+func (s *callSiteSampler) allow(cfg *CallSiteSampling, l *Log) bool {
+	maxLevel := cfg.MaxLevel
+	if maxLevel == LevelUnknown {
+		maxLevel = LevelDebug
+	}
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); !ok || lvl > maxLevel {
+		return true
+	}
+	key, ok := l.Data[DataKeySrcFileLine].(string)
+	if !ok {
+		frame, found := callerFrame(l.callerSkip)
+		if !found {
+			return true
+		}
+		key = frame.File + ":" + strconv.Itoa(frame.Line)
+	}
+
+	if s.sites == nil {
+		s.sites = map[string]*callSiteState{}
+	}
+	site, ok := s.sites[key]
+	if !ok {
+		site = &callSiteState{next: 1}
+		s.sites[key] = site
+	}
+	site.count++
+	if site.count < site.next {
+		return false
+	}
+	base := uint64(cfg.Base)
+	if base < 2 {
+		base = 10
+	}
+	l.Data[DataKeyOccurrence] = site.count
+	if dropped := site.count - site.last - 1; dropped > 0 {
+		l.Data[DataKeyDropped] = dropped
+	}
+	site.last, site.next = site.count, site.next*base
+	return true
+}
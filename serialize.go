@@ -0,0 +1,117 @@
+package logs
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Serializer can convert a Log to bytes so that it can be written.
+type Serializer func(*Log) []byte
+
+// Returns the JSON representation of a log with line breaks and indentations.
+// Self-referential data values are rendered as "<cycle>" where they repeat.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsPrettyJSON(l *Log) []byte { return prettyEncoder.Encode(l) }
+
+// Returns a single-line-JSON representation of a log.
+// Self-referential data values are rendered as "<cycle>" where they repeat.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsJSON(l *Log) []byte { return defaultEncoder.Encode(l) }
+
+// Returns a single-line-JSON representation of a log, suitable for NDJSON ingestion
+// (line breaks in the message and data are escaped, so each log spans exactly one line).
+// It is equivalent to AsJSON and exists to make the intent explicit in logger configurations.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsCompactJSON(l *Log) []byte { return defaultEncoder.Encode(l) }
+
+// Returns a single-line-JSON representation of a log with a stable field order, easier to diff and grep:
+// the timestamp, level and message come first, followed by the other data keys sorted alphabetically
+// (in a single object, without the nested "data" object of AsJSON).
+// Logs serialized this way can be read with NewReader.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsOrderedJSON(l *Log) []byte { return orderedEncoder.Encode(l) }
+
+// JSONOptions configures the JSON serializer returned by AsJSONWith.
+type JSONOptions struct {
+	Indent              string // For ex: "\t" (leave empty for single-line JSON)
+	DisableHTMLEscaping bool   // For ex: true to keep "<", ">" and "&" as is in URLs
+}
+
+// Returns a JSON serializer configured with the given options.
+// The returned serializer will panic if the JSON encoding of the log returns an error.
+func AsJSONWith(opts JSONOptions) Serializer {
+	return (&Encoder{Indent: opts.Indent, DisableHTMLEscaping: opts.DisableHTMLEscaping}).Serializer()
+}
+
+// Returns a single-line textual representation of a log.
+// Data fields are separated by commas (the leading comma is omitted when the message is empty),
+// the timestamp and level come first and the other data keys are sorted alphabetically.
+func AsPlainText(l *Log) []byte {
+	out := l.Message
+	for _, k := range sortedDataKeys(l) {
+		if out != "" {
+			out += ", "
+		}
+		out += k + ": " + l.textValue(k, l.Data[k])
+	}
+	return []byte(out)
+}
+
+// Returns the JSON representation of a log following the OpenTelemetry log data model.
+// The message is stored as the body, the timestamp and level are mapped
+// to their OpenTelemetry equivalent and the remaining data is stored as attributes.
+// This function will panic if the JSON marshalling of the log returns an error.
+func AsOTelJSON(l *Log) []byte {
+	b, err := json.Marshal(newOTelLog(l))
+	if err != nil {
+		b, err = json.Marshal(newOTelLog(withoutCycles(l)))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newOTelLog converts a log to the OpenTelemetry log data model.
+func newOTelLog(l *Log) any {
+	type OTelLog struct {
+		TimeUnixNano   string         `json:"timeUnixNano,omitempty"`
+		SeverityNumber int            `json:"severityNumber,omitempty"`
+		SeverityText   string         `json:"severityText,omitempty"`
+		Body           string         `json:"body"`
+		Attributes     map[string]any `json:"attributes,omitempty"`
+	}
+
+	out := OTelLog{Body: l.Message, Attributes: map[string]any{}}
+	for k, v := range l.Data {
+		switch k {
+		case DataKeyTimestamp:
+			if t, ok := v.(time.Time); ok {
+				out.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+				continue
+			}
+		case DataKeyLevel:
+			if lvl, ok := levelOf(v); ok {
+				out.SeverityNumber = otelSeverityNumbers[baseLevel(lvl)]
+				out.SeverityText = lvl.String()
+				continue
+			}
+		}
+		out.Attributes[k] = stringify(v, false)
+	}
+	return out
+}
+
+// Maps log levels to OpenTelemetry severity numbers (the first number of the range of each level).
+// Panic and fatal logs both map to FATAL (21).
+var otelSeverityNumbers = map[LogLevel]int{
+	LevelUnknown: 0,
+	LevelTrace:   1,
+	LevelDebug:   5,
+	LevelInfo:    9,
+	LevelWarn:    13,
+	LevelError:   17,
+	LevelPanic:   21,
+	LevelFatal:   21,
+}
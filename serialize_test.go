@@ -0,0 +1,30 @@
+package logs
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOTelSeverityNumbers(t *testing.T) {
+	want := map[LogLevel]int{
+		LevelTrace: 1,
+		LevelDebug: 5,
+		LevelInfo:  9,
+		LevelWarn:  13,
+		LevelError: 17,
+		LevelPanic: 21,
+		LevelFatal: 21,
+	}
+	for lvl, number := range want {
+		var out struct {
+			SeverityNumber int    `json:"severityNumber"`
+			SeverityText   string `json:"severityText"`
+		}
+		if err := json.Unmarshal(AsOTelJSON(NewLog("msg", WithLevel(lvl.String()))), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.SeverityNumber != number || out.SeverityText != lvl.String() {
+			t.Errorf("%s: got %d %q, want %d %q", lvl, out.SeverityNumber, out.SeverityText, number, lvl.String())
+		}
+	}
+}
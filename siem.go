@@ -0,0 +1,154 @@
+package logs
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SIEMOptions configures the CEF and LEEF serializers (see AsCEF and AsLEEF).
+type SIEMOptions struct {
+	Vendor          string            // For ex: "Acme"
+	Product         string            // For ex: "Billing API"
+	Version         string            // For ex: "1.4.2"
+	EventIDKey      string            // For ex: "event" to use this data value as the signature / event ID (the level label by default, UNKNOWN without level)
+	Fields          map[string]string // For ex: {"user_id": "suser", "remote_addr": "src"} to map data keys to the keys of the format
+	ExcludeUnmapped bool              // For ex: true to only write the data keys present in Fields
+}
+
+// Maps log levels to CEF and LEEF severities (from 0 to 10).
+var siemSeverities = map[LogLevel]int{
+	LevelUnknown: 3,
+	LevelTrace:   0,
+	LevelDebug:   1,
+	LevelInfo:    3,
+	LevelWarn:    5,
+	LevelError:   7,
+	LevelPanic:   9,
+	LevelFatal:   10,
+}
+
+// Returns a serializer writing logs in the ArcSight Common Event Format (CEF) version 0,
+// for ex: to send them to a SIEM through syslog (see writers.Syslog).
+//
+// The header holds the vendor, product and version of the options, the event ID (see SIEMOptions.EventIDKey),
+// the message (as the event name) and the severity of the level.
+// The extension holds the message (under "msg"), the creation time (under "rt", in milliseconds since the Unix epoch)
+// and the other data, under the key given by SIEMOptions.Fields or else its own key (without the "__" prefix of this package).
+// Values that are not strings are encoded as JSON.
+func AsCEF(opts SIEMOptions) Serializer {
+	return func(l *Log) []byte {
+		eventID, severity, ext := siemFields(l, opts, "rt")
+		b := []byte("CEF:0")
+		for _, field := range []string{opts.Vendor, opts.Product, opts.Version, eventID, l.Message, strconv.Itoa(severity)} {
+			b = append(b, '|')
+			b = append(b, cefHeaderEscaper.Replace(field)...)
+		}
+		b = append(b, '|')
+		for i, kv := range ext {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = append(b, siemKey(kv[0])...)
+			b = append(b, '=')
+			b = append(b, cefValueEscaper.Replace(kv[1])...)
+		}
+		return b
+	}
+}
+
+// Returns a serializer writing logs in the IBM QRadar Log Event Extended Format (LEEF) version 1.0,
+// for ex: to send them to a SIEM through syslog (see writers.Syslog).
+//
+// The header holds the vendor, product and version of the options and the event ID (see SIEMOptions.EventIDKey).
+// The attributes (separated by tabs) hold the severity of the level (under "sev"), the message (under "msg"),
+// the creation time (under "devTime", in milliseconds since the Unix epoch)
+// and the other data, under the key given by SIEMOptions.Fields or else its own key (without the "__" prefix of this package).
+// Values that are not strings are encoded as JSON.
+func AsLEEF(opts SIEMOptions) Serializer {
+	return func(l *Log) []byte {
+		eventID, severity, attrs := siemFields(l, opts, "devTime")
+		b := []byte("LEEF:1.0")
+		for _, field := range []string{opts.Vendor, opts.Product, opts.Version, eventID} {
+			b = append(b, '|')
+			b = append(b, leefHeaderEscaper.Replace(field)...)
+		}
+		b = append(b, "|sev="...)
+		b = strconv.AppendInt(b, int64(severity), 10)
+		for _, kv := range attrs {
+			b = append(b, '\t')
+			b = append(b, siemKey(kv[0])...)
+			b = append(b, '=')
+			b = append(b, leefValueEscaper.Replace(kv[1])...)
+		}
+		return b
+	}
+}
+
+// Escape special characters of the CEF and LEEF formats.
+var (
+	cefHeaderEscaper  = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper   = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r", `\r`, "\n", `\n`)
+	leefHeaderEscaper = strings.NewReplacer("|", `\|`, "\t", " ", "\r", " ", "\n", " ")
+	leefValueEscaper  = strings.NewReplacer("\t", `\t`, "\r", `\r`, "\n", `\n`)
+	siemKeyReplacer   = strings.NewReplacer(" ", "_", "=", "_", "|", "_", "\t", "_", "\r", "_", "\n", "_")
+)
+
+// siemKey returns a key without the characters that are not allowed in CEF and LEEF keys.
+func siemKey(key string) string { return siemKeyReplacer.Replace(key) }
+
+// siemFields returns the event ID, severity and key-value pairs of a log,
+// the creation time is stored under timeKey.
+func siemFields(l *Log, opts SIEMOptions, timeKey string) (eventID string, severity int, kvs [][2]string) {
+	severity, eventID = siemSeverities[LevelUnknown], LevelUnknown.String()
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok {
+		severity, eventID = siemSeverities[baseLevel(lvl)], lvl.String()
+	}
+	if v, ok := l.Data[opts.EventIDKey]; ok && opts.EventIDKey != "" {
+		eventID = siemValue(v)
+	}
+
+	kvs = append(kvs, [2]string{"msg", l.Message})
+	for _, k := range sortedDataKeys(l) {
+		v := l.Data[k]
+		if k == DataKeyTimestamp {
+			if t, ok := v.(time.Time); ok {
+				kvs = append(kvs, [2]string{timeKey, strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)})
+				continue
+			}
+		}
+		if k == DataKeyLevel || k == opts.EventIDKey {
+			continue
+		}
+		name, mapped := opts.Fields[k]
+		if !mapped {
+			if opts.ExcludeUnmapped {
+				continue
+			}
+			name = strings.TrimPrefix(k, dataKeyPrefix)
+		}
+		kvs = append(kvs, [2]string{name, siemValue(v)})
+	}
+	return eventID, severity, kvs
+}
+
+// siemValue returns the textual representation of a data value.
+func siemValue(v any) string {
+	switch v := stringify(v, false).(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case LogLevel:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return sprint(v)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build go1.21
+
+package logs
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+)
+
+// NewSlogHandler returns a slog.Handler writing records with the given logger,
+// so that applications using log/slog can use the serializers and writers of this package.
+//
+// Record levels are mapped to the closest level at or below them (for ex: slog.LevelWarn+2 becomes LevelWarn),
+// attributes are stored as data (groups as nested maps) and the source location reported by slog is stored
+// under DataKeySrcFunction and DataKeySrcFileLine.
+func NewSlogHandler(cfg *DefaultLogger) slog.Handler {
+	fn, err := cfg.LoggerFunc()
+	return &slogHandler{cfg: cfg, fn: fn, err: err, data: map[string]any{}}
+}
+
+// slogHandler implements slog.Handler.
+type slogHandler struct {
+	cfg    *DefaultLogger
+	fn     LoggerFunc
+	err    error          // Returned by Handle if the logger func could not be created
+	data   map[string]any // Attributes added with WithAttrs
+	groups []string       // Groups opened with WithGroup
+}
+
+// Enabled reports whether records with the given level are written.
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return levelFromSlog(lvl) >= h.cfg.MinLevel()
+}
+
+// Handle writes a record.
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	l := NewLog(r.Message, WithLevel(levelFromSlog(r.Level).String()))
+	for k, v := range copySlogData(h.data) {
+		l.Data[k] = v
+	}
+	if !r.Time.IsZero() {
+		l.Data[DataKeyTimestamp] = r.Time
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		l.Data[DataKeySrcFunction] = frame.Function
+		l.Data[DataKeySrcFileLine] = frame.File + ":" + strconv.Itoa(frame.Line)
+	}
+
+	if r.NumAttrs() > 0 {
+		group := slogGroup(l.Data, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addSlogAttr(group, a)
+			return true
+		})
+	}
+	return h.fn(l)
+}
+
+// WithAttrs returns a handler adding the given attributes to all records.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.data = copySlogData(h.data)
+	group := slogGroup(h2.data, h.groups)
+	for _, a := range attrs {
+		addSlogAttr(group, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler nesting the attributes added afterwards in the given group.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// levelFromSlog returns the log level matching a slog level.
+func levelFromSlog(lvl slog.Level) LogLevel {
+	switch {
+	case lvl < slog.LevelDebug:
+		return LevelTrace
+	case lvl < slog.LevelInfo:
+		return LevelDebug
+	case lvl < slog.LevelWarn:
+		return LevelInfo
+	case lvl < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
+
+// slogGroup returns the map holding the attributes of the given (nested) groups, creating it if needed.
+func slogGroup(data map[string]any, groups []string) map[string]any {
+	for _, name := range groups {
+		sub, ok := data[name].(map[string]any)
+		if !ok {
+			sub = map[string]any{}
+			data[name] = sub
+		}
+		data = sub
+	}
+	return data
+}
+
+// addSlogAttr adds an attribute to a map, groups are stored as nested maps.
+func addSlogAttr(data map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		data[a.Key] = logValue(a.Value.Any(), 0)
+		return
+	}
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	group := data // inline groups without key
+	if a.Key != "" {
+		group = slogGroup(data, []string{a.Key})
+	}
+	for _, sub := range attrs {
+		addSlogAttr(group, sub)
+	}
+}
+
+// copySlogData copies the attributes of a handler, including nested groups.
+func copySlogData(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		if group, ok := v.(map[string]any); ok {
+			v = copySlogData(group)
+		}
+		out[k] = v
+	}
+	return out
+}
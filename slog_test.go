@@ -0,0 +1,22 @@
+//go:build go1.21
+
+package logs
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandlerConvertsLoggable(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewSlogHandler(&DefaultLogger{Writers: []io.Writer{&buf}, Serializer: AsJSON}))
+	user := testUser{ID: 1, Password: "SECRET"}
+	logger.Info("msg", slog.Any("user", user), slog.Group("g", slog.Any("users", []testUser{user})))
+	got := buf.String()
+	if strings.Contains(got, "SECRET") || !strings.Contains(got, `"user":{"id":1}`) || !strings.Contains(got, `"users":[{"id":1}]`) {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -0,0 +1,155 @@
+package logs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// Data keys of the logs of spans.
+// The trace and span IDs of OpenTelemetry spans are stored under the same keys (see the otel contrib).
+const (
+	DataKeySpan         = dataKeyPrefix + "span"           // Name of the span
+	DataKeySpanEvent    = dataKeyPrefix + "span_event"     // "start" or "end"
+	DataKeySpanID       = dataKeyPrefix + "span_id"        // ID of the span
+	DataKeyParentSpanID = dataKeyPrefix + "parent_span_id" // ID of the parent span (for nested spans)
+	DataKeyTraceID      = dataKeyPrefix + "trace_id"       // ID of the root span, shared by all the spans of a tree
+	DataKeySpanDepth    = dataKeyPrefix + "span_depth"     // Nesting depth of the span (0 for a root span)
+)
+
+// Span measures an operation and writes a log when it ends with its duration (under DataKeyDurationMS),
+// for ex: sp := log.Span("fetch user"); defer sp.End().
+// It is lightweight tracing for applications without OpenTelemetry:
+// the logs of spans hold their ID, the ID of their parent span and of their root span (the trace ID) and their nesting depth.
+//
+// Spans are created with LoggerFunc.Span or LoggerFunc.SpanWithStart (which also writes a log when the span starts),
+// nested spans are created with Span.Span (or from a context, see StartSpan).
+// Their logs are written at info level, unless another level is given in the options.
+type Span struct {
+	name      string
+	log       LoggerFunc
+	opts      []LogOption
+	id        string
+	parentID  string
+	traceID   string
+	depth     int
+	withStart bool
+	timer     Timer
+	endOnce   sync.Once
+}
+
+// Span starts a span with the given name, its options are added to its logs.
+func (fn LoggerFunc) Span(name string, opts ...LogOption) *Span {
+	return newSpan(fn, nil, name, false, opts)
+}
+
+// SpanWithStart is like Span but also writes a log when the span starts (and so do its nested spans).
+func (fn LoggerFunc) SpanWithStart(name string, opts ...LogOption) *Span {
+	return newSpan(fn, nil, name, true, opts)
+}
+
+// Span starts a span with the given name, see LoggerFunc.Span.
+func (dl *DefaultLogger) Span(name string, opts ...LogOption) *Span {
+	return dl.loggerFunc().Span(name, opts...)
+}
+
+// SpanWithStart starts a span with the given name, see LoggerFunc.SpanWithStart.
+func (dl *DefaultLogger) SpanWithStart(name string, opts ...LogOption) *Span {
+	return dl.loggerFunc().SpanWithStart(name, opts...)
+}
+
+// StartSpan starts a span nested in the span of the context (if any, otherwise a root span is started)
+// and returns a copy of the context holding the new span.
+func (fn LoggerFunc) StartSpan(ctx context.Context, name string, opts ...LogOption) (*Span, context.Context) {
+	var sp *Span
+	if parent, ok := SpanFromContext(ctx); ok {
+		sp = parent.Span(name, opts...)
+	} else {
+		sp = fn.Span(name, opts...)
+	}
+	return sp, ContextWithSpan(ctx, sp)
+}
+
+// Span starts a span nested in this one, its logs are written with the same logger func.
+func (sp *Span) Span(name string, opts ...LogOption) *Span {
+	return newSpan(sp.log, sp, name, sp.withStart, opts)
+}
+
+// newSpan starts a span, writing its start log if needed.
+func newSpan(fn LoggerFunc, parent *Span, name string, withStart bool, opts []LogOption) *Span {
+	sp := &Span{name: name, log: fn, opts: opts, id: newSpanID(), withStart: withStart}
+	sp.traceID = sp.id
+	if parent != nil {
+		sp.parentID, sp.traceID, sp.depth = parent.id, parent.traceID, parent.depth+1
+	}
+	if withStart {
+		fn(sp.newLog("start"))
+	}
+	sp.timer = StartTimer()
+	return sp
+}
+
+// ID returns the ID of the span.
+func (sp *Span) ID() string { return sp.id }
+
+// TraceID returns the ID of the root span of the span.
+func (sp *Span) TraceID() string { return sp.traceID }
+
+// End writes the log of the end of the span with its duration and the given options (for ex: WithError).
+// Only the first call writes a log.
+func (sp *Span) End(opts ...LogOption) error {
+	var err error
+	sp.endOnce.Do(func() {
+		l := sp.newLog("end", opts...)
+		WithTimer(sp.timer)(l)
+		err = sp.log(l)
+	})
+	return err
+}
+
+// LoggerFunc returns a logger func adding the IDs of the span to logs (see WithSpan), for ex: to log events during the span.
+func (sp *Span) LoggerFunc() LoggerFunc { return sp.log.With(WithSpan(sp)) }
+
+// newLog returns a log of the span.
+func (sp *Span) newLog(event string, opts ...LogOption) *Log {
+	l := NewLog(sp.name, WithLevel(LevelInfo.String()), WithSpan(sp), WithData(DataKeySpan, sp.name), WithData(DataKeySpanEvent, event))
+	if sp.parentID != "" {
+		l.Data[DataKeyParentSpanID] = sp.parentID
+	}
+	l.Data[DataKeySpanDepth] = sp.depth
+	for _, opt := range sp.opts {
+		opt(l)
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// WithSpan adds the ID of the span and of its root span to the log.
+func WithSpan(sp *Span) LogOption {
+	return func(l *Log) {
+		l.Data[DataKeySpanID] = sp.id
+		l.Data[DataKeyTraceID] = sp.traceID
+	}
+}
+
+// ContextWithSpan returns a copy of the context holding the given span.
+// The IDs of the span are added to logs created with WithContext.
+func ContextWithSpan(ctx context.Context, sp *Span) context.Context {
+	return context.WithValue(ctx, contextKeySpan, sp)
+}
+
+// SpanFromContext returns the span stored in the context (if any).
+func SpanFromContext(ctx context.Context) (*Span, bool) {
+	sp, ok := ctx.Value(contextKeySpan).(*Span)
+	return sp, ok
+}
+
+// newSpanID returns a random span ID (8 bytes encoded in hexadecimal, like OpenTelemetry span IDs).
+func newSpanID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
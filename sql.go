@@ -0,0 +1,425 @@
+package logs
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLSink writes logs to a table of a SQL database, one row per log, so that logs can be queried with SQL.
+// It implements LogWriter: use it as a writer of a DefaultLogger (writes of serialized JSON logs are also accepted).
+// Fields must be set before the first write.
+//
+// Rows hold the creation time, the level label and value (severity), the message and the other data as a JSON object.
+//
+// When BatchSize is greater than 1, rows are inserted by batches (with multi-row inserts) from a background goroutine:
+// Flush or Close must be called to make sure all logs are inserted (DefaultLogger.Flush and Close do it).
+// When QueueSize batches are already waiting to be inserted, writes block until the database catches up.
+// Inserts failing with a transient error (for ex: a lost connection or a deadlock) are retried with an exponential backoff.
+type SQLSink struct {
+	BatchSize     int           // For ex: 500 rows per insert (1 by default with SQLite: each log is inserted when written, 100 with PostgreSQL)
+	FlushInterval time.Duration // For ex: 5s to insert incomplete batches periodically (1s by default)
+	QueueSize     int           // For ex: 16 batches waiting to be inserted before writes block (4 by default)
+	MaxRetries    int           // For ex: 10 attempts after the first one (3 by default, a negative number disables retries)
+	OnError       func(error)   // For ex: write a fallback line to stderr (errors of background inserts are ignored by default)
+
+	db        *sql.DB
+	table     string
+	dialect   sqlDialect
+	mu        sync.Mutex
+	rows      [][]any // Rows of the current batch
+	queue     chan sqlBatch
+	inflight  sync.WaitGroup // Writes queueing a batch
+	startOnce sync.Once
+	stopped   chan struct{}
+	closed    bool
+}
+
+// sqlBatch is a batch of rows waiting to be inserted, done (if not nil) receives the result once it is inserted.
+type sqlBatch struct {
+	rows [][]any
+	done chan error
+}
+
+// sqlDialect holds the queries specific to a database.
+type sqlDialect struct {
+	schema      []string // Queries creating the table and indexes, %[1]s stands for the table name
+	placeholder func(n int) string
+	maxParams   int                   // Maximum number of parameters of a query
+	createdAt   func(t time.Time) any // Value of the creation time column
+	batchSize   int                   // Default batch size
+}
+
+// Used by NewSQLiteSink.
+var sqliteDialect = sqlDialect{
+	schema: []string{
+		`CREATE TABLE IF NOT EXISTS %[1]s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at TEXT,
+			level TEXT,
+			severity INTEGER,
+			message TEXT NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_level ON %[1]s (level, created_at)`,
+	},
+	placeholder: func(int) string { return "?" },
+	maxParams:   999,
+	createdAt:   func(t time.Time) any { return t.UTC().Format(sqlTimeFormat) },
+	batchSize:   1,
+}
+
+// Used by NewPostgresSink.
+var postgresDialect = sqlDialect{
+	schema: []string{
+		`CREATE TABLE IF NOT EXISTS %[1]s (
+			id BIGSERIAL PRIMARY KEY,
+			created_at TIMESTAMPTZ,
+			level TEXT,
+			severity INTEGER,
+			message TEXT NOT NULL,
+			data JSONB NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_level ON %[1]s (level, created_at)`,
+	},
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	maxParams:   65535,
+	createdAt:   func(t time.Time) any { return t },
+	batchSize:   100,
+}
+
+// Format of the creation time of logs in SQLite tables
+// (in the RFC 3339 format with a fixed number of decimals, so that times sort as text).
+const sqlTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Number of columns inserted per log.
+const sqlColumns = 5
+
+// Matches valid table names.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLiteSink returns a sink writing logs to the given table of a SQLite database,
+// the table and its indexes (on the creation time and level) are created if they don't exist.
+// The database must be opened with a SQLite driver registered by the application, for ex:
+//
+//	import _ "modernc.org/sqlite"
+//
+//	db, err := sql.Open("sqlite", "logs.db")
+//	sink, err := logs.NewSQLiteSink(db, "logs")
+//
+// Logs can then be queried with SQL, for ex:
+//
+//	SELECT created_at, message FROM logs WHERE severity >= 40 AND json_extract(data, '$.user_id') = '42'
+func NewSQLiteSink(db *sql.DB, table string) (*SQLSink, error) {
+	sink, err := newSQLSink(db, table, sqliteDialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := sink.createSchema(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// NewPostgresSink returns a sink writing logs by batches to the given table of a PostgreSQL database
+// (the data is stored as JSONB), the table must exist (see CreatePostgresSchema).
+// The database must be opened with a PostgreSQL driver registered by the application, for ex:
+//
+//	import _ "github.com/jackc/pgx/v5/stdlib"
+//
+//	db, err := sql.Open("pgx", "postgres://localhost/app")
+//	sink, err := logs.NewPostgresSink(db, "logs")
+//
+// Logs can then be queried with SQL, for ex:
+//
+//	SELECT created_at, message FROM logs WHERE severity >= 40 AND data->>'user_id' = '42'
+func NewPostgresSink(db *sql.DB, table string) (*SQLSink, error) {
+	return newSQLSink(db, table, postgresDialect)
+}
+
+// CreatePostgresSchema creates the table used by NewPostgresSink and its indexes (on the creation time and level)
+// if they don't exist, for ex: when the application starts or in a migration.
+func CreatePostgresSchema(db *sql.DB, table string) error {
+	sink, err := newSQLSink(db, table, postgresDialect)
+	if err != nil {
+		return err
+	}
+	return sink.createSchema()
+}
+
+// newSQLSink returns a sink writing to the given table.
+func newSQLSink(db *sql.DB, table string, dialect sqlDialect) (*SQLSink, error) {
+	if !sqlTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &SQLSink{db: db, table: table, dialect: dialect, stopped: make(chan struct{})}, nil
+}
+
+// createSchema creates the table and its indexes if they don't exist.
+func (s *SQLSink) createSchema() error {
+	for _, query := range s.dialect.schema {
+		if _, err := s.db.Exec(fmt.Sprintf(query, s.table)); err != nil {
+			return fmt.Errorf("create log table: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteLog inserts the log in the table (b is ignored), or adds it to the current batch.
+func (s *SQLSink) WriteLog(l *Log, b []byte) error {
+	row, err := s.row(l)
+	if err != nil {
+		return err
+	}
+	if s.batchSize() <= 1 {
+		return s.insertWithRetries([][]any{row})
+	}
+	s.startOnce.Do(s.start)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	s.rows = append(s.rows, row)
+	var full [][]any
+	if len(s.rows) >= s.batchSize() {
+		full, s.rows = s.rows, nil
+		s.inflight.Add(1)
+	}
+	s.mu.Unlock()
+
+	if full != nil {
+		defer s.inflight.Done()
+		s.queue <- sqlBatch{rows: full}
+	}
+	return nil
+}
+
+// Write decodes a log serialized as JSON (see NewReader) and inserts it in the table.
+func (s *SQLSink) Write(b []byte) (int, error) {
+	l, err := NewReader(bytes.NewReader(b)).Next()
+	if err != nil {
+		return 0, fmt.Errorf("decode log: %w", err)
+	}
+	if err := s.WriteLog(l, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush inserts the current batch and waits until all queued batches are inserted.
+func (s *SQLSink) Flush() error {
+	if s.batchSize() <= 1 {
+		return nil
+	}
+	s.startOnce.Do(s.start)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	rows := s.rows
+	s.rows = nil
+	s.inflight.Add(1)
+	s.mu.Unlock()
+
+	defer s.inflight.Done()
+	done := make(chan error, 1)
+	s.queue <- sqlBatch{rows: rows, done: done}
+	return <-done
+}
+
+// Close inserts the current batch, waits until all queued batches are inserted and stops the background goroutine.
+// The database is left open, it belongs to the application.
+func (s *SQLSink) Close() error {
+	if s.batchSize() <= 1 {
+		return nil
+	}
+	s.startOnce.Do(s.start)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	rows := s.rows
+	s.rows = nil
+	s.mu.Unlock()
+
+	s.inflight.Wait()
+	done := make(chan error, 1)
+	s.queue <- sqlBatch{rows: rows, done: done}
+	close(s.queue)
+	err := <-done
+	<-s.stopped
+	return err
+}
+
+// start creates the queue and starts the background goroutine inserting batches.
+func (s *SQLSink) start() {
+	size := s.QueueSize
+	if size <= 0 {
+		size = 4
+	}
+	interval := s.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s.queue = make(chan sqlBatch, size)
+
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case batch, ok := <-s.queue:
+				if !ok {
+					return
+				}
+				err := s.insertWithRetries(batch.rows)
+				if batch.done != nil {
+					batch.done <- err
+				} else if err != nil {
+					s.report(err)
+				}
+			case <-ticker.C:
+				s.mu.Lock()
+				rows := s.rows
+				s.rows = nil
+				s.mu.Unlock()
+				if err := s.insertWithRetries(rows); err != nil {
+					s.report(err)
+				}
+			}
+		}
+	}()
+}
+
+// insertWithRetries inserts rows, retrying with an exponential backoff when the error is transient.
+func (s *SQLSink) insertWithRetries(rows [][]any) error {
+	retries := s.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := s.insert(rows)
+		if err == nil || attempt >= retries || !isTransientSQLError(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 10*time.Second {
+			backoff = 10 * time.Second
+		}
+	}
+}
+
+// insert inserts rows with as few multi-row inserts as the parameter limit of the database allows.
+func (s *SQLSink) insert(rows [][]any) error {
+	perQuery := s.dialect.maxParams / sqlColumns
+	for len(rows) > 0 {
+		n := len(rows)
+		if n > perQuery {
+			n = perQuery
+		}
+
+		var query strings.Builder
+		fmt.Fprintf(&query, "INSERT INTO %s (created_at, level, severity, message, data) VALUES ", s.table)
+		args := make([]any, 0, n*sqlColumns)
+		for i, row := range rows[:n] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteByte('(')
+			for j := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				query.WriteString(s.dialect.placeholder(len(args) + j + 1))
+			}
+			query.WriteByte(')')
+			args = append(args, row...)
+		}
+		if _, err := s.db.Exec(query.String(), args...); err != nil {
+			return fmt.Errorf("insert logs: %w", err)
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// isTransientSQLError reports whether inserting again may succeed:
+// connection errors and PostgreSQL errors of the connection, transaction rollback,
+// insufficient resources and operator intervention classes (see the SQLState method of the drivers errors).
+func isTransientSQLError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		for _, class := range []string{"08", "40", "53", "57P"} {
+			if strings.HasPrefix(state, class) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// report passes an error of the background goroutine to OnError (if set).
+func (s *SQLSink) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// batchSize returns the configured batch size or the default one of the dialect.
+func (s *SQLSink) batchSize() int {
+	if s.BatchSize <= 0 {
+		return s.dialect.batchSize
+	}
+	return s.BatchSize
+}
+
+// row returns the column values of a log: creation time, level, severity, message and data.
+// Missing values are NULL.
+func (s *SQLSink) row(l *Log) ([]any, error) {
+	var createdAt, level, severity any
+	data := defaultEncoder.prepare(l).Data
+	if t, ok := l.Data[DataKeyTimestamp].(time.Time); ok { // Read before preparing, which formats times (see TimeFormat)
+		createdAt = s.dialect.createdAt(t)
+		delete(data, DataKeyTimestamp)
+	}
+	if lvl, ok := levelOf(l.Data[DataKeyLevel]); ok {
+		level, severity = lvl.String(), int64(lvl)
+		delete(data, DataKeyLevel)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		b, err = json.Marshal(withoutCycles(&Log{Data: data}).Data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode log data: %w", err)
+	}
+	return []any{createdAt, level, severity, l.Message, string(b)}, nil
+}
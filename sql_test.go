@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSQLSinkRow(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	tests := []struct {
+		name       string
+		timeFormat *TimeFormat
+	}{
+		{"default time format", nil},
+		{"unix time format", &TimeFormat{Layout: TimeUnixMilli}},
+		{"custom layout", &TimeFormat{Layout: time.Kitchen}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, err := newSQLSink(nil, "logs", sqliteDialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := NewLog("msg", WithLevel(LevelWarn.String()), WithData("user_id", "42"))
+			l.Data[DataKeyTimestamp] = ts
+			l.timeFormat = tt.timeFormat
+
+			row, err := sink.row(l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "2024-05-01T10:30:00.000000000Z"; row[0] != want {
+				t.Errorf("created_at = %v, want %v", row[0], want)
+			}
+			if row[1] != "WARN" || row[2] != int64(LevelWarn) || row[3] != "msg" {
+				t.Errorf("level, severity, message = %v, %v, %v", row[1], row[2], row[3])
+			}
+			var data map[string]any
+			if err := json.Unmarshal([]byte(row[4].(string)), &data); err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 1 || data["user_id"] != "42" {
+				t.Errorf("data = %v, want only user_id", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package logs
+
+import (
+	"log"
+	"strings"
+)
+
+// NewStdLogger returns a standard library logger writing each of its lines as a log with the given level,
+// so third-party code that only accepts a *log.Logger (for ex: http.Server.ErrorLog) writes structured logs.
+// The returned logger has no prefix or flags, timestamps and other data come from the logger func.
+func NewStdLogger(fn LoggerFunc, lvl LogLevel) *log.Logger {
+	return log.New(&stdLogWriter{fn: fn, lvl: lvl}, "", 0)
+}
+
+// stdLogWriter writes the lines of a standard library logger as logs.
+type stdLogWriter struct {
+	fn  LoggerFunc
+	lvl LogLevel
+}
+
+// Write writes b (without its trailing line break) as a log message.
+// The standard library logger calls Write once per line.
+func (w *stdLogWriter) Write(b []byte) (int, error) {
+	if err := w.fn.logAt(w.lvl, strings.TrimSuffix(string(b), "\n"), nil); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,81 @@
+package logs
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DataKeyElapsed    = dataKeyPrefix + "elapsed"
+	DataKeyLaps       = dataKeyPrefix + "laps"
+	DataKeyDurationMS = dataKeyPrefix + "duration_ms"
+)
+
+// Stopwatch measures the time elapsed since it was started.
+// It can also record intermediate splits (laps), for ex: to profile the phases of a request.
+type Stopwatch struct {
+	start time.Time
+	mu    sync.Mutex
+	laps  map[string]time.Duration
+}
+
+// StartStopwatch returns a new stopwatch started at the current time.
+func StartStopwatch() *Stopwatch { return &Stopwatch{start: time.Now()} }
+
+// Lap records the time elapsed since the stopwatch was started under the given name.
+func (sw *Stopwatch) Lap(name string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.laps == nil {
+		sw.laps = map[string]time.Duration{}
+	}
+	sw.laps[name] = time.Since(sw.start)
+}
+
+// WithElapsed adds the time elapsed since the stopwatch was started to the log,
+// along with the recorded laps (if any).
+func WithElapsed(sw *Stopwatch) LogOption {
+	return func(l *Log) {
+		l.Data[DataKeyElapsed] = time.Since(sw.start)
+
+		sw.mu.Lock()
+		defer sw.mu.Unlock()
+		if len(sw.laps) == 0 {
+			return
+		}
+		laps := make(map[string]time.Duration, len(sw.laps))
+		for name, d := range sw.laps {
+			laps[name] = d
+		}
+		l.Data[DataKeyLaps] = laps
+	}
+}
+
+// Timer measures the latency of an operation, to log it as a number (unlike a time.Duration formatted as a string).
+// For ex: timer := logs.StartTimer(); defer log(logs.NewLog("op done", timer.Elapsed("duration_ms"))).
+type Timer struct{ start time.Time }
+
+// StartTimer returns a timer started at the current time.
+func StartTimer() Timer { return Timer{start: time.Now()} }
+
+// Duration returns the time elapsed since the timer was started.
+func (t Timer) Duration() time.Duration { return time.Since(t.start) }
+
+// Elapsed adds the time elapsed since the timer was started to the log, in milliseconds (as a float64).
+func (t Timer) Elapsed(key string) LogOption { return t.ElapsedIn(key, time.Millisecond) }
+
+// ElapsedIn adds the time elapsed since the timer was started to the log, in the given unit (as a float64),
+// for ex: time.Second or time.Microsecond.
+func (t Timer) ElapsedIn(key string, unit time.Duration) LogOption {
+	return func(l *Log) { l.set(key, float64(time.Since(t.start))/float64(unit)) }
+}
+
+// WithTimer adds the time elapsed since the timer was started to the log, in milliseconds (under DataKeyDurationMS).
+func WithTimer(t Timer) LogOption { return t.Elapsed(DataKeyDurationMS) }
+
+// LogDuration starts a timer and returns a function writing a log with the given message and options
+// along with the time elapsed (see WithTimer), for ex: defer logs.LogDuration(log, "fetch user")().
+func LogDuration(fn LoggerFunc, msg string, opts ...LogOption) func() error {
+	t := StartTimer()
+	return func() error { return fn(NewLog(msg, append(append([]LogOption(nil), opts...), WithTimer(t))...)) }
+}
@@ -0,0 +1,65 @@
+package logs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingWriter records each write separately.
+type recordingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (cw *recordingWriter) Write(b []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.writes = append(cw.writes, string(b))
+	return len(b), nil
+}
+
+func TestStreamSerializerWritesLogsWithoutBuffering(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		cw := &recordingWriter{}
+		dl := &DefaultLogger{Writers: []io.Writer{cw}, StreamSerializer: SerializeTo, LogPrefix: "> ", LogSuffix: " <", Async: async}
+		for i := 0; i < 10; i++ {
+			l := NewLog("msg", WithData("i", i))
+			if err := dl.Log(l); err != nil {
+				t.Fatal(err)
+			}
+			l.Data["i"] = "mutated after the call" // The log belongs to the caller again
+		}
+		if err := dl.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(cw.writes) != 30 {
+			t.Fatalf("async=%v: %d writes, want the prefix, log and suffix of 10 logs", async, len(cw.writes))
+		}
+		lines := strings.Split(strings.TrimSuffix(strings.Join(cw.writes, ""), "\n"), "\n")
+		if len(lines) != 10 {
+			t.Fatalf("async=%v: %d lines, want 10", async, len(lines))
+		}
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "> {") || !strings.HasSuffix(line, "} <") || strings.Contains(line, "mutated") {
+				t.Errorf("async=%v: unexpected line %q", async, line)
+			}
+		}
+	}
+}
+
+func TestStreamSerializerErrorIsSerializationError(t *testing.T) {
+	var buf bytes.Buffer
+	failing := func(l *Log, w io.Writer) error { return errors.New("boom") }
+	dl := &DefaultLogger{Writers: []io.Writer{&buf}, Serializer: AsJSON, StreamSerializer: failing}
+	err := dl.Info("msg")
+	if !errors.Is(err, ErrSerialize) {
+		t.Fatalf("err = %v, want ErrSerialize", err)
+	}
+	if !strings.Contains(buf.String(), DataKeySerializeError) {
+		t.Fatalf("substitute log not written: %q", buf.String())
+	}
+}
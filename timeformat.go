@@ -0,0 +1,49 @@
+package logs
+
+import "time"
+
+// Special layouts of TimeFormat serializing times as numbers.
+const (
+	TimeUnix      = "unix"       // Seconds since the Unix epoch
+	TimeUnixMilli = "unix_milli" // Milliseconds since the Unix epoch
+	TimeUnixMicro = "unix_micro" // Microseconds since the Unix epoch
+	TimeUnixNano  = "unix_nano"  // Nanoseconds since the Unix epoch
+)
+
+// TimeFormat configures how the timestamp and the other time.Time data values of logs are serialized
+// by the JSON, plain text, console and CSV serializers of this package (see DefaultLogger.TimeFormat).
+// Serializers of protocols defining their own time format (for ex: AsGELF or AsOTelJSON) are not affected.
+type TimeFormat struct {
+	Layout   string         // For ex: time.RFC3339, "2006-01-02 15:04:05" or TimeUnixMilli (time.RFC3339Nano by default)
+	Location *time.Location // For ex: time.Local (UTC by default)
+}
+
+// Format returns the serialized time: a string, or an int64 for the Unix layouts.
+func (f *TimeFormat) Format(t time.Time) any {
+	loc := f.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	switch f.Layout {
+	case "":
+		return t.Format(time.RFC3339Nano)
+	case TimeUnix:
+		return t.Unix()
+	case TimeUnixMilli:
+		return t.UnixMilli()
+	case TimeUnixMicro:
+		return t.UnixMicro()
+	case TimeUnixNano:
+		return t.UnixNano()
+	}
+	return t.Format(f.Layout)
+}
+
+// formatTime returns a data value of the log, formatted with its time format if it is a time (and the log has one).
+func (l *Log) formatTime(v any) any {
+	if t, ok := v.(time.Time); ok && l.timeFormat != nil {
+		return l.timeFormat.Format(t)
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ValueFormatter formats data values in the text serializers (AsPlainText and AsConsole), see DefaultLogger.ValueFormatter.
+// It returns false to leave a value to the default formatting (with fmt, times are formatted with the TimeFormat of the logger).
+// JSON serializers are not affected: values remain numbers there.
+type ValueFormatter func(key string, v any) (string, bool)
+
+// HumanValues formats durations with 3 significant digits (for ex: "1.23s" instead of "1.234567891s", durations from 100s are rounded to the second)
+// and byte sizes (see ByteSize) with binary units (for ex: "4.5MiB").
+func HumanValues(key string, v any) (string, bool) {
+	switch v := v.(type) {
+	case time.Duration:
+		return roundDuration(v).String(), true
+	case ByteSize:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// ByteSize is a number of bytes, written with binary units by text serializers (for ex: "4.5MiB")
+// and as a number by JSON serializers.
+type ByteSize int64
+
+// WithBytes adds a number of bytes to the log (see ByteSize).
+func WithBytes(key string, n int64) LogOption { return WithData(key, ByteSize(n)) }
+
+// String returns the size with a binary unit and one decimal (for ex: "512B", "1.5KiB" or "4.5MiB").
+func (b ByteSize) String() string {
+	const units = "KMGTPE"
+	sign, n := "", uint64(b)
+	if b < 0 {
+		sign, n = "-", uint64(-b)
+	}
+	if n < 1024 {
+		return sign + strconv.FormatUint(n, 10) + "B"
+	}
+	size, i := float64(n)/1024, 0
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+	return fmt.Sprintf("%s%.1f%ciB", sign, size, units[i])
+}
+
+// roundDuration rounds a duration to 3 significant digits (to the second from 100s).
+func roundDuration(d time.Duration) time.Duration {
+	abs := d
+	if abs < 0 {
+		abs = -abs
+	}
+	for unit := time.Duration(1); unit < time.Second; unit *= 10 {
+		if abs < 1000*unit {
+			return d.Round(unit)
+		}
+	}
+	return d.Round(time.Second)
+}
+
+// textValue formats a data value of the log for a text serializer,
+// with its value formatter (if any) or fmt (with its time format for times).
+// Self-referential values are rendered with "<cycle>" where they repeat (see sprint).
+func (l *Log) textValue(key string, v any) string {
+	if l.formatter != nil {
+		if s, ok := l.formatter(key, v); ok {
+			return s
+		}
+	}
+	return sprint(l.formatTime(v))
+}
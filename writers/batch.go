@@ -0,0 +1,129 @@
+package writers
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// BatchWriter accumulates writes in memory and writes them to the underlying writer at once,
+// when the batch gets big enough or old enough, this reduces the number of syscalls and network round trips.
+// Each write is kept whole: a batch is only flushed between writes.
+type BatchWriter struct {
+	w        io.Writer
+	maxBytes int
+	buf      []byte
+	err      error // Error of the last background flush, returned by the next call to Flush or Close
+	done     chan struct{}
+	stopped  chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Batch returns a writer writing batches to w once they hold at least maxBytes,
+// or every interval when the batch isn't empty (0 means batches are only flushed when full).
+func Batch(w io.Writer, maxBytes int, interval time.Duration) *BatchWriter {
+	bw := &BatchWriter{w: w, maxBytes: maxBytes, done: make(chan struct{}), stopped: make(chan struct{})}
+	if interval <= 0 {
+		close(bw.stopped)
+		return bw
+	}
+	go func() {
+		defer close(bw.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-bw.done:
+				return
+			case <-ticker.C:
+				bw.mu.Lock()
+				if err := bw.flush(); err != nil {
+					bw.err = err
+				}
+				bw.mu.Unlock()
+			}
+		}
+	}()
+	return bw
+}
+
+// Write adds b to the current batch, flushing it if it is full.
+// Errors of background flushes are returned by Flush and Close instead, as they don't concern b.
+func (bw *BatchWriter) Write(b []byte) (int, error) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if bw.closed {
+		return 0, os.ErrClosed
+	}
+	bw.buf = append(bw.buf, b...)
+	if len(bw.buf) >= bw.maxBytes {
+		if err := bw.flush(); err != nil {
+			return len(b), err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes the current batch to the underlying writer (and flushes it if it implements Flush() error).
+// An error of a previous background flush is returned (once) if there was one.
+func (bw *BatchWriter) Flush() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	err := bw.takeErr()
+	if ferr := bw.flush(); err == nil {
+		err = ferr
+	}
+	if f, ok := bw.w.(interface{ Flush() error }); ok && err == nil {
+		err = f.Flush()
+	}
+	return err
+}
+
+// Close stops the background flushes, writes the current batch
+// and closes the underlying writer (if it implements io.Closer).
+func (bw *BatchWriter) Close() error {
+	bw.mu.Lock()
+	if bw.closed {
+		bw.mu.Unlock()
+		return nil
+	}
+	bw.closed = true
+	bw.mu.Unlock()
+
+	close(bw.done)
+	<-bw.stopped
+
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	err := bw.takeErr()
+	if ferr := bw.flush(); err == nil {
+		err = ferr
+	}
+	if c, ok := bw.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// flush writes the current batch, it must be called while holding the lock.
+// The batch is dropped if the write fails, so a failing destination doesn't make it grow forever.
+func (bw *BatchWriter) flush() error {
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	_, err := bw.w.Write(bw.buf)
+	bw.buf = bw.buf[:0]
+	return err
+}
+
+// takeErr returns and clears the error of the last background flush.
+func (bw *BatchWriter) takeErr() error {
+	err := bw.err
+	bw.err = nil
+	return err
+}
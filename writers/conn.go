@@ -0,0 +1,172 @@
+package writers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReconnectPolicy defines what happens to logs written while a connection is down.
+type ReconnectPolicy int
+
+const (
+	BufferWhileReconnecting ReconnectPolicy = iota // Keep logs in memory and send them once reconnected
+	DropWhileReconnecting                          // Drop logs until the connection is back
+)
+
+// ErrConnDown is returned when a log is dropped because the connection is down.
+var ErrConnDown = errors.New("connection down")
+
+// Largest frame written by ConnWriter and accepted by ReadFramedLogs,
+// this protects readers against huge allocations on corrupted or hostile streams.
+const maxFrameSize = 64 << 20
+
+// ConnWriter writes length-prefixed frames to a network connection (for ex: to a log-forwarding sidecar).
+// Each call to Write produces one frame: a 4-byte big-endian length followed by the written bytes,
+// so the reader can split records even when they contain newlines (see ReadFramedLogs).
+//
+// When the connection fails, it is closed and re-dialed on subsequent writes with an exponential backoff.
+type ConnWriter struct {
+	Dial        func() (net.Conn, error) // For ex: dial a unix socket (defaults to re-dialing the remote address of the initial connection)
+	Policy      ReconnectPolicy          // For ex: drop logs while reconnecting
+	MaxBuffered int                      // For ex: 1000 frames kept in memory while reconnecting (0 means unlimited)
+	MinBackoff  time.Duration            // For ex: 100ms before the first reconnection attempt
+	MaxBackoff  time.Duration            // For ex: 30s between reconnection attempts at most
+
+	mu          sync.Mutex
+	conn        net.Conn
+	buffered    [][]byte
+	backoff     time.Duration
+	nextAttempt time.Time
+}
+
+// NewConnWriter returns a new ConnWriter using the given connection.
+func NewConnWriter(conn net.Conn) *ConnWriter {
+	addr := conn.RemoteAddr()
+	return &ConnWriter{
+		Dial:        func() (net.Conn, error) { return net.Dial(addr.Network(), addr.String()) },
+		MaxBuffered: 1000,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		conn:        conn,
+	}
+}
+
+// Write sends b as a single frame, an error is returned if b is larger than 64 MiB.
+func (cw *ConnWriter) Write(b []byte) (int, error) {
+	if len(b) > maxFrameSize {
+		return 0, fmt.Errorf("frame too large: %d bytes", len(b))
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	frame := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(frame, uint32(len(b)))
+	copy(frame[4:], b)
+
+	// Send previously buffered frames first to preserve ordering
+	if cw.connect() {
+		for len(cw.buffered) > 0 {
+			if err := cw.send(cw.buffered[0]); err != nil {
+				break
+			}
+			cw.buffered = cw.buffered[1:]
+		}
+	}
+	if cw.conn != nil && len(cw.buffered) == 0 && cw.send(frame) == nil {
+		return len(b), nil
+	}
+
+	// Connection is down
+	if cw.Policy == DropWhileReconnecting {
+		return 0, ErrConnDown
+	}
+	if cw.MaxBuffered > 0 && len(cw.buffered) >= cw.MaxBuffered {
+		return 0, fmt.Errorf("%w: buffer is full", ErrConnDown)
+	}
+	cw.buffered = append(cw.buffered, frame)
+	return len(b), nil
+}
+
+// Close closes the underlying connection, buffered frames are discarded.
+func (cw *ConnWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.buffered = nil
+	if cw.conn == nil {
+		return nil
+	}
+	err := cw.conn.Close()
+	cw.conn = nil
+	return err
+}
+
+// connect re-dials the connection if needed and if the backoff delay has passed.
+// It reports whether a connection is available.
+func (cw *ConnWriter) connect() bool {
+	if cw.conn != nil {
+		return true
+	}
+	if time.Now().Before(cw.nextAttempt) {
+		return false
+	}
+	conn, err := cw.Dial()
+	if err != nil {
+		cw.backoff *= 2
+		if cw.backoff < cw.MinBackoff {
+			cw.backoff = cw.MinBackoff
+		}
+		if cw.MaxBackoff > 0 && cw.backoff > cw.MaxBackoff {
+			cw.backoff = cw.MaxBackoff
+		}
+		cw.nextAttempt = time.Now().Add(cw.backoff)
+		return false
+	}
+	cw.conn, cw.backoff = conn, 0
+	return true
+}
+
+// send writes a whole frame to the connection, closing it on failure.
+func (cw *ConnWriter) send(frame []byte) error {
+	for len(frame) > 0 {
+		n, err := cw.conn.Write(frame)
+		if err != nil {
+			cw.conn.Close()
+			cw.conn = nil
+			return err
+		}
+		frame = frame[n:]
+	}
+	return nil
+}
+
+// ReadFramedLogs reads all the frames written by a ConnWriter until the end of the reader.
+// An error is returned along with the previous frames if a frame is larger than 64 MiB.
+func ReadFramedLogs(r io.Reader) ([][]byte, error) {
+	var frames [][]byte
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > maxFrameSize {
+			return frames, fmt.Errorf("invalid frame size: %d", size)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return frames, err
+		}
+		frames = append(frames, frame)
+	}
+}
@@ -0,0 +1,2 @@
+// Package writers provides io.Writer implementations to use as log outputs.
+package writers
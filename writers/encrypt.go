@@ -0,0 +1,202 @@
+package writers
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrUnknownKey is returned when a record is encrypted with a key that is not in the keyring.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Largest encrypted record accepted by DecryptReader, this protects against huge allocations on corrupted files.
+const maxEncryptedRecordSize = 64 << 20
+
+// Keyring holds the AES keys used to encrypt and decrypt records, identified by an ID.
+// Keys can be rotated: new records are encrypted with the current key (see Use)
+// while records encrypted with previous keys can still be decrypted as long as their key is in the keyring.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[uint32]cipher.AEAD
+	current uint32
+	hasKey  bool
+}
+
+// NewKeyring returns an empty keyring.
+func NewKeyring() *Keyring { return &Keyring{keys: map[uint32]cipher.AEAD{}} }
+
+// Add adds an AES key (16, 24 or 32 bytes long) with the given ID.
+// The first key added is used to encrypt records until Use is called.
+func (kr *Keyring) Add(id uint32, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, exists := kr.keys[id]; exists {
+		return fmt.Errorf("encryption key %d already exists", id)
+	}
+	kr.keys[id] = aead
+	if !kr.hasKey {
+		kr.current, kr.hasKey = id, true
+	}
+	return nil
+}
+
+// Use sets the key used to encrypt new records.
+func (kr *Keyring) Use(id uint32) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownKey, id)
+	}
+	kr.current = id
+	return nil
+}
+
+// Remove removes a key, records encrypted with it can't be decrypted anymore.
+// The current key cannot be removed.
+func (kr *Keyring) Remove(id uint32) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if kr.hasKey && id == kr.current {
+		return fmt.Errorf("encryption key %d is in use", id)
+	}
+	delete(kr.keys, id)
+	return nil
+}
+
+// key returns the key with the given ID.
+func (kr *Keyring) key(id uint32) (cipher.AEAD, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	aead, ok := kr.keys[id]
+	return aead, ok
+}
+
+// currentKey returns the key used to encrypt records and its ID.
+func (kr *Keyring) currentKey() (uint32, cipher.AEAD, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if !kr.hasKey {
+		return 0, nil, errors.New("keyring is empty")
+	}
+	return kr.current, kr.keys[kr.current], nil
+}
+
+// EncryptWriter encrypts each write as a separate record with AES-GCM (with a random nonce) before writing it.
+// A record is made of its length (4 bytes, big-endian), the ID of the key (4 bytes, big-endian, also authenticated),
+// the nonce and the ciphertext, it can be decrypted with DecryptReader.
+type EncryptWriter struct {
+	w  io.Writer
+	kr *Keyring
+	mu sync.Mutex
+}
+
+// Encrypt returns a writer encrypting writes with the current key of the keyring before writing them to w.
+func Encrypt(w io.Writer, kr *Keyring) *EncryptWriter { return &EncryptWriter{w: w, kr: kr} }
+
+// Write encrypts b and writes the record to the underlying writer (with a single call to its Write method).
+func (ew *EncryptWriter) Write(b []byte) (int, error) {
+	id, aead, err := ew.kr.currentKey()
+	if err != nil {
+		return 0, err
+	}
+
+	record := make([]byte, 8+aead.NonceSize(), 8+aead.NonceSize()+len(b)+aead.Overhead())
+	binary.BigEndian.PutUint32(record[4:8], id)
+	nonce := record[8:]
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	record = aead.Seal(record, nonce, b, record[4:8])
+	binary.BigEndian.PutUint32(record[:4], uint32(len(record)-4))
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if _, err := ew.w.Write(record); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the underlying writer (if it implements io.Closer).
+func (ew *EncryptWriter) Close() error {
+	if c, ok := ew.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// DecryptReader reads records written by an EncryptWriter and decrypts them.
+// It can be used as an io.Reader of the decrypted content (for ex: to read logs with logs.NewReader)
+// or record by record with Next.
+type DecryptReader struct {
+	r       *bufio.Reader
+	kr      *Keyring
+	pending []byte // Decrypted content not read yet
+}
+
+// Decrypt returns a reader decrypting the records read from r with the keys of the keyring.
+func Decrypt(r io.Reader, kr *Keyring) *DecryptReader {
+	return &DecryptReader{r: bufio.NewReader(r), kr: kr}
+}
+
+// Next returns the content of the next record, or io.EOF when there are no more records.
+// io.ErrUnexpectedEOF is returned if the last record is truncated.
+func (dr *DecryptReader) Next() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(dr.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size < 4 || size > maxEncryptedRecordSize {
+		return nil, fmt.Errorf("invalid encrypted record size: %d", size)
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(dr.r, record); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	id := binary.BigEndian.Uint32(record[:4])
+	aead, ok := dr.kr.key(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKey, id)
+	}
+	if len(record) < 4+aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted record size: %d", size)
+	}
+	nonce, ciphertext := record[4:4+aead.NonceSize()], record[4+aead.NonceSize():]
+	b, err := aead.Open(ciphertext[:0], nonce, ciphertext, record[:4])
+	if err != nil {
+		return nil, fmt.Errorf("decrypt record: %w", err)
+	}
+	return b, nil
+}
+
+// Read reads the decrypted content of the records.
+func (dr *DecryptReader) Read(p []byte) (int, error) {
+	for len(dr.pending) == 0 {
+		b, err := dr.Next()
+		if err != nil {
+			return 0, err
+		}
+		dr.pending = b
+	}
+	n := copy(p, dr.pending)
+	dr.pending = dr.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,117 @@
+package writers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// newTestKeyring returns a keyring holding a key for each of the given IDs.
+func newTestKeyring(t *testing.T, ids ...uint32) *Keyring {
+	t.Helper()
+	kr := NewKeyring()
+	for _, id := range ids {
+		if err := kr.Add(id, bytes.Repeat([]byte{byte(id)}, 32)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return kr
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	kr := newTestKeyring(t, 1)
+	var buf bytes.Buffer
+	ew := Encrypt(&buf, kr)
+	write(t, ew, "first\n")
+	write(t, ew, "second\n")
+
+	dr := Decrypt(bytes.NewReader(buf.Bytes()), kr)
+	for _, want := range []string{"first\n", "second\n"} {
+		b, err := dr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("record = %q, want %q", b, want)
+		}
+	}
+	if _, err := dr.Next(); err != io.EOF {
+		t.Fatalf("after the last record: %v, want %v", err, io.EOF)
+	}
+
+	b, err := io.ReadAll(Decrypt(bytes.NewReader(buf.Bytes()), kr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "first\nsecond\n" {
+		t.Fatalf("decrypted content = %q, want %q", b, "first\nsecond\n")
+	}
+}
+
+func TestEncryptUsesUniqueNonces(t *testing.T) {
+	kr := newTestKeyring(t, 1)
+	var buf bytes.Buffer
+	ew := Encrypt(&buf, kr)
+	for i := 0; i < 100; i++ {
+		write(t, ew, "same content")
+	}
+
+	seen := map[string]bool{}
+	for b := buf.Bytes(); len(b) > 0; {
+		size := binary.BigEndian.Uint32(b[:4])
+		nonce := string(b[8:20]) // After the length and key ID, GCM nonces are 12 bytes long
+		if seen[nonce] {
+			t.Fatalf("nonce %x used twice", nonce)
+		}
+		seen[nonce] = true
+		b = b[4+size:]
+	}
+	if len(seen) != 100 {
+		t.Fatalf("%d records, want 100", len(seen))
+	}
+}
+
+func TestDecryptWithRotatedKeys(t *testing.T) {
+	kr := newTestKeyring(t, 1, 2)
+	var buf bytes.Buffer
+	ew := Encrypt(&buf, kr)
+	write(t, ew, "old key\n")
+	if err := kr.Use(2); err != nil {
+		t.Fatal(err)
+	}
+	write(t, ew, "new key\n")
+
+	b, err := io.ReadAll(Decrypt(bytes.NewReader(buf.Bytes()), kr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "old key\nnew key\n" {
+		t.Fatalf("decrypted content = %q, want %q", b, "old key\nnew key\n")
+	}
+
+	if err := kr.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(bytes.NewReader(buf.Bytes()), kr).Next(); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("decrypt with a removed key: %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestDecryptRejectsTamperedRecords(t *testing.T) {
+	kr := newTestKeyring(t, 1)
+	var buf bytes.Buffer
+	write(t, Encrypt(&buf, kr), "content")
+
+	tampered := buf.Bytes()
+	tampered[len(tampered)-1] ^= 1
+	if _, err := Decrypt(bytes.NewReader(tampered), kr).Next(); err == nil {
+		t.Fatal("tampered record decrypted without error")
+	}
+
+	truncated := buf.Bytes()[:buf.Len()-5]
+	if _, err := Decrypt(bytes.NewReader(truncated), kr).Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated record: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
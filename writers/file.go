@@ -0,0 +1,239 @@
+package writers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncPolicy determines when a file writer commits its content to stable storage (with fsync),
+// trading durability for throughput.
+type SyncPolicy struct {
+	everyWrite bool
+	interval   time.Duration
+}
+
+var (
+	SyncNever      = SyncPolicy{}                 // Only sync when Sync is called (the default)
+	SyncEveryWrite = SyncPolicy{everyWrite: true} // Flush and sync after each write (slowest, most durable)
+)
+
+// SyncInterval returns a policy flushing and syncing the file periodically,
+// at most the logs written during the last interval are lost on crash.
+func SyncInterval(d time.Duration) SyncPolicy { return SyncPolicy{interval: d} }
+
+// Default buffer size of file writers.
+const DefaultFileBufferSize = 64 * 1024
+
+// Size of the header of framed records (length and checksum).
+const recordHeaderSize = 8
+
+// Largest framed record written by FileWriter and accepted by ReadRecords,
+// this protects against huge allocations when reading a header torn by a crash.
+const maxRecordSize = 64 << 20
+
+// ErrCorruptRecord is returned when reading a framed record whose checksum doesn't match its content.
+var ErrCorruptRecord = errors.New("corrupt record")
+
+// FileWriter is a buffered file writer with a configurable flush interval and sync policy.
+// Fields must be set before the first write.
+//
+// When Framed is true, each write is stored as a record prefixed with its length and CRC-32 checksum
+// (4 bytes each, big-endian) so that a record torn by a crash can be detected and ignored by ReadRecords.
+type FileWriter struct {
+	BufferSize    int           // For ex: 1 MB buffered in memory before writing to the file (DefaultFileBufferSize by default)
+	FlushInterval time.Duration // For ex: 1s to write the buffer to the file periodically (0 means only when it's full)
+	SyncPolicy    SyncPolicy    // For ex: SyncInterval(time.Second) (SyncNever by default)
+	Framed        bool          // For ex: true to detect torn or corrupted records after a crash
+
+	mu        sync.Mutex
+	f         *os.File
+	w         *bufio.Writer
+	startOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+	closed    bool
+}
+
+// NewFileWriter opens (or creates) the file at the given path, new logs are appended to it.
+func NewFileWriter(path string) (*FileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWriter{f: f, done: make(chan struct{}), stopped: make(chan struct{})}, nil
+}
+
+// Write buffers b (as a single record if framing is enabled), then flushes and syncs it according to the sync policy.
+// When framing is enabled, an error is returned if b is larger than 64 MiB.
+func (fw *FileWriter) Write(b []byte) (int, error) {
+	if fw.Framed && len(b) > maxRecordSize {
+		return 0, fmt.Errorf("record too large: %d bytes", len(b))
+	}
+	fw.startOnce.Do(fw.start)
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closed {
+		return 0, os.ErrClosed
+	}
+
+	if fw.Framed {
+		var header [recordHeaderSize]byte
+		binary.BigEndian.PutUint32(header[:4], uint32(len(b)))
+		binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(b))
+		if _, err := fw.w.Write(header[:]); err != nil {
+			return 0, err
+		}
+	}
+	n, err := fw.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	if fw.SyncPolicy.everyWrite {
+		return n, fw.sync()
+	}
+	return n, nil
+}
+
+// start creates the buffer and starts the background goroutine flushing and syncing the file periodically (if needed).
+func (fw *FileWriter) start() {
+	size := fw.BufferSize
+	if size <= 0 {
+		size = DefaultFileBufferSize
+	}
+	fw.mu.Lock()
+	fw.w = bufio.NewWriterSize(fw.f, size)
+	fw.mu.Unlock()
+
+	if fw.FlushInterval <= 0 && fw.SyncPolicy.interval <= 0 {
+		close(fw.stopped)
+		return
+	}
+	go func() {
+		defer close(fw.stopped)
+		var flushTick, syncTick <-chan time.Time // Nil channels block forever
+		if fw.FlushInterval > 0 {
+			t := time.NewTicker(fw.FlushInterval)
+			defer t.Stop()
+			flushTick = t.C
+		}
+		if fw.SyncPolicy.interval > 0 {
+			t := time.NewTicker(fw.SyncPolicy.interval)
+			defer t.Stop()
+			syncTick = t.C
+		}
+		for {
+			select {
+			case <-fw.done:
+				return
+			case <-flushTick:
+				fw.Flush()
+			case <-syncTick:
+				fw.Sync()
+			}
+		}
+	}()
+}
+
+// Flush writes the buffered data to the file.
+func (fw *FileWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.w == nil || fw.closed {
+		return nil
+	}
+	return fw.w.Flush()
+}
+
+// Sync writes the buffered data to the file and commits it to stable storage.
+func (fw *FileWriter) Sync() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closed {
+		return nil
+	}
+	return fw.sync()
+}
+
+// sync flushes the buffer and syncs the file, it must be called while holding the lock.
+func (fw *FileWriter) sync() error {
+	if fw.w != nil {
+		if err := fw.w.Flush(); err != nil {
+			return err
+		}
+	}
+	return fw.f.Sync()
+}
+
+// Close stops the background goroutine, writes the buffered data and closes the file.
+// The file is synced first unless the sync policy is SyncNever.
+func (fw *FileWriter) Close() error {
+	fw.startOnce.Do(func() { close(fw.stopped) })
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return nil
+	}
+	fw.closed = true
+	fw.mu.Unlock()
+
+	close(fw.done)
+	<-fw.stopped
+
+	var err error
+	if fw.w != nil {
+		err = fw.w.Flush()
+	}
+	if err == nil && fw.SyncPolicy != SyncNever {
+		err = fw.f.Sync()
+	}
+	if cerr := fw.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadRecords returns the records written to a file by a framed FileWriter.
+// A truncated record at the end (for ex: after a crash) is ignored.
+// ErrCorruptRecord is returned along with the previous records if a checksum doesn't match
+// or if a header holds a length larger than 64 MiB (which FileWriter never writes).
+// Records are read as they come, so a torn header is never trusted for a large allocation.
+func ReadRecords(r io.Reader) ([][]byte, error) {
+	var records [][]byte
+	br := bufio.NewReader(r)
+	for {
+		var header [recordHeaderSize]byte
+		if _, err := io.ReadFull(br, header[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		size := binary.BigEndian.Uint32(header[:4])
+		if size > maxRecordSize {
+			return records, ErrCorruptRecord
+		}
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, br, int64(size)); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		record := buf.Bytes()
+		if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			return records, ErrCorruptRecord
+		}
+		records = append(records, record)
+	}
+}
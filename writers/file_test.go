@@ -0,0 +1,108 @@
+package writers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileWriterCreatesDirectories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a", "b", "app.log")
+	fw, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, fw, "created\n")
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "created\n" {
+		t.Fatalf("file = %q, want %q", got, "created\n")
+	}
+}
+
+func TestFileWriterPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fw, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&^0o644 != 0 || perm&0o600 != 0o600 {
+		t.Fatalf("file permissions = %v, want at most -rw-r--r-- (and readable and writable by the owner)", perm)
+	}
+}
+
+func TestFileWriterAppendsWhenReopened(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for _, line := range []string{"first\n", "second\n"} {
+		fw, err := NewFileWriter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(t, fw, line)
+		if err := fw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readFile(t, path); got != "first\nsecond\n" {
+		t.Fatalf("file = %q, want %q", got, "first\nsecond\n")
+	}
+}
+
+func TestFileWriterWriteAfterClose(t *testing.T) {
+	fw, err := NewFileWriter(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("too late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("write after close: %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestFileWriterFramedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fw, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Framed = true
+	write(t, fw, "first")
+	write(t, fw, "second\nwith a newline")
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a record torn by a crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 10, 1, 2, 3, 4, 't', 'o'})
+	f.Close()
+
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := ReadRecords(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{[]byte("first"), []byte("second\nwith a newline")}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %q, want %q", records, want)
+	}
+}
@@ -0,0 +1,99 @@
+package writers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Default chunk size of GELF UDP writers, suitable for most networks (including WANs).
+const DefaultGELFChunkSize = 1420
+
+// Maximum number of chunks of a GELF message (as accepted by Graylog).
+const maxGELFChunks = 128
+
+// ErrGELFMessageTooLarge is returned when a GELF message would need more than 128 chunks.
+var ErrGELFMessageTooLarge = errors.New("gelf message too large")
+
+// GELFWriter sends each write as a GELF message over UDP, for ex: serialized with logs.AsGELF.
+// Messages larger than the chunk size are split into GELF chunks.
+// A trailing line break (as added by loggers) is removed before sending a message.
+type GELFWriter struct {
+	ChunkSize int  // For ex: 8154 on a LAN (DefaultGELFChunkSize by default)
+	Compress  bool // For ex: true to gzip messages before sending them
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// GELFUDP returns a writer sending GELF messages to the given address (for ex: "graylog:12201").
+func GELFUDP(addr string) (*GELFWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &GELFWriter{conn: conn}, nil
+}
+
+// Write sends b as a GELF message.
+func (gw *GELFWriter) Write(b []byte) (int, error) {
+	msg := bytes.TrimSuffix(b, []byte("\n"))
+	if gw.Compress {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(msg); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		msg = buf.Bytes()
+	}
+
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	// Send message as is if it fits in a single datagram
+	size := gw.ChunkSize
+	if size <= 0 {
+		size = DefaultGELFChunkSize
+	}
+	if len(msg) <= size {
+		if _, err := gw.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	// Otherwise split it into chunks (with a 12 byte header each)
+	data := size - 12
+	count := (len(msg) + data - 1) / data
+	if count > maxGELFChunks {
+		return 0, ErrGELFMessageTooLarge
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return 0, err
+	}
+	chunk := make([]byte, 0, size)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * data
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*data:end]...)
+		if _, err := gw.conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Close closes the underlying connection.
+func (gw *GELFWriter) Close() error { return gw.conn.Close() }
@@ -0,0 +1,384 @@
+package writers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default batch size of HTTP writers.
+const DefaultHTTPBatchSize = 1 << 20
+
+// HTTPWriter sends logs to an HTTP endpoint, as batches of newline-delimited logs (NDJSON) in POST requests.
+// Fields must be set before the first write.
+//
+// Batches are sent from a background goroutine, failed requests are retried with an exponential backoff.
+// When the endpoint stays unreachable, batches are spilled to SpillDir (if set) and sent again later, oldest first.
+// When QueueSize batches are already waiting to be sent, Write blocks until the endpoint catches up (backpressure).
+type HTTPWriter struct {
+	Client        *http.Client  // For ex: a client with a custom transport (a client with a 10s timeout by default)
+	Header        http.Header   // For ex: an "Authorization" header added to all requests
+	Gzip          bool          // For ex: true to compress request bodies (sent with "Content-Encoding: gzip")
+	BatchSize     int           // For ex: 512 KB of logs per request (DefaultHTTPBatchSize by default)
+	FlushInterval time.Duration // For ex: 5s to send incomplete batches periodically (1s by default)
+	QueueSize     int           // For ex: 16 batches waiting to be sent before Write blocks (4 by default)
+	MaxRetries    int           // For ex: 10 attempts after the first one (3 by default, a negative number disables retries)
+	MinBackoff    time.Duration // For ex: 1s before the first retry (500ms by default), doubled after each retry
+	MaxBackoff    time.Duration // For ex: 1m between retries at most (30s by default)
+	SpillDir      string        // For ex: "/var/spool/app-logs" (batches that couldn't be sent are dropped by default)
+	MaxSpillBytes int64         // For ex: 1 GB of spilled batches, new batches are dropped beyond (0 means no limit)
+	OnError       func(error)   // For ex: write a fallback line to stderr (errors are ignored by default)
+
+	url       string
+	mu        sync.Mutex
+	buf       []byte
+	queue     chan httpBatch
+	queueMu   sync.Mutex // Held from taking a batch from buf until it is queued, so that batches are queued in order
+	startOnce sync.Once
+	stopped   chan struct{}
+	closed    bool
+}
+
+// httpBatch is a batch waiting to be sent, done (if not nil) receives the result once it is sent.
+// Periodic batches are sent after the spilled batches (see HTTPWriter.FlushInterval).
+type httpBatch struct {
+	b        []byte
+	done     chan error
+	periodic bool
+}
+
+// NewHTTPWriter returns a writer sending logs to the given URL.
+func NewHTTPWriter(url string) *HTTPWriter {
+	return &HTTPWriter{url: url, stopped: make(chan struct{})}
+}
+
+// Write adds b to the current batch (as a line), queueing the batch if it is full.
+func (hw *HTTPWriter) Write(b []byte) (int, error) {
+	hw.startOnce.Do(hw.start)
+
+	hw.mu.Lock()
+	if hw.closed {
+		hw.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+	hw.buf = append(hw.buf, b...)
+	if !bytes.HasSuffix(hw.buf, []byte("\n")) {
+		hw.buf = append(hw.buf, '\n')
+	}
+	if len(hw.buf) < hw.batchSize() {
+		hw.mu.Unlock()
+		return len(b), nil
+	}
+	full := hw.buf
+	hw.buf = nil
+	hw.queueMu.Lock()
+	hw.mu.Unlock()
+
+	hw.queue <- httpBatch{b: full}
+	hw.queueMu.Unlock()
+	return len(b), nil
+}
+
+// Flush sends the current batch and waits until all queued batches are sent (or spilled).
+func (hw *HTTPWriter) Flush() error {
+	hw.startOnce.Do(hw.start)
+
+	hw.mu.Lock()
+	if hw.closed {
+		hw.mu.Unlock()
+		return nil
+	}
+	b := hw.buf
+	hw.buf = nil
+	hw.queueMu.Lock()
+	hw.mu.Unlock()
+
+	done := make(chan error, 1)
+	hw.queue <- httpBatch{b: b, done: done}
+	hw.queueMu.Unlock()
+	return <-done
+}
+
+// Close sends the current batch and waits until all queued batches are sent (or spilled).
+func (hw *HTTPWriter) Close() error {
+	hw.startOnce.Do(hw.start)
+
+	hw.mu.Lock()
+	if hw.closed {
+		hw.mu.Unlock()
+		return nil
+	}
+	hw.closed = true
+	b := hw.buf
+	hw.buf = nil
+	hw.queueMu.Lock()
+	hw.mu.Unlock()
+
+	done := make(chan error, 1)
+	hw.queue <- httpBatch{b: b, done: done}
+	close(hw.queue)
+	hw.queueMu.Unlock()
+	err := <-done
+	<-hw.stopped
+	return err
+}
+
+// start creates the queue and starts the background goroutines sending batches and queueing the current batch periodically.
+func (hw *HTTPWriter) start() {
+	size := hw.QueueSize
+	if size <= 0 {
+		size = 4
+	}
+	interval := hw.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	hw.queue = make(chan httpBatch, size)
+
+	go func() {
+		defer close(hw.stopped)
+		hw.resend() // Batches spilled by a previous process
+		for batch := range hw.queue {
+			if batch.periodic {
+				hw.resend() // Before newer logs, to keep them in order
+			}
+			err := hw.send(batch.b)
+			if batch.done != nil {
+				batch.done <- err
+			} else if err != nil {
+				hw.report(err)
+			}
+		}
+	}()
+
+	// The current batch goes through the queue, after the full batches queued before it
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hw.stopped:
+				return
+			case <-ticker.C:
+			}
+			hw.mu.Lock()
+			if hw.closed {
+				hw.mu.Unlock()
+				return
+			}
+			b := hw.buf
+			hw.buf = nil
+			hw.queueMu.Lock()
+			hw.mu.Unlock()
+			hw.queue <- httpBatch{b: b, periodic: true}
+			hw.queueMu.Unlock()
+		}
+	}()
+}
+
+// send posts a batch, retrying on failure, and spills it to disk if it still cannot be sent.
+func (hw *HTTPWriter) send(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	err := hw.postWithRetries(b)
+	if err == nil || hw.SpillDir == "" || isPermanent(err) {
+		return err
+	}
+	if serr := hw.spill(b); serr != nil {
+		return fmt.Errorf("%w (and spill failed: %v)", err, serr)
+	}
+	return nil
+}
+
+// postWithRetries posts a batch, retrying with an exponential backoff when the error is temporary.
+func (hw *HTTPWriter) postWithRetries(b []byte) error {
+	retries, backoff, maxBackoff := hw.MaxRetries, hw.MinBackoff, hw.MaxBackoff
+	if retries == 0 {
+		retries = 3
+	}
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err := hw.post(b)
+		if err == nil || attempt >= retries || isPermanent(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post sends a single request.
+func (hw *HTTPWriter) post(b []byte) error {
+	body := b
+	if hw.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(b)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hw.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range hw.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if hw.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	client := hw.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body) // Allow connection reuse
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &httpStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return nil
+}
+
+// Client used by HTTP writers without a client.
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// httpStatusError is returned when the endpoint answers with a non-2xx status.
+type httpStatusError struct {
+	code   int
+	status string
+}
+
+func (err *httpStatusError) Error() string { return "unexpected HTTP status: " + err.status }
+
+// isPermanent reports whether the endpoint rejected a request, so that sending it again would fail again
+// (client errors, except timeouts and rate limiting).
+func isPermanent(err error) bool {
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return statusErr.code < 500
+}
+
+// Extension of spilled batches.
+const spillExt = ".ndjson"
+
+// spill stores a batch in the spill directory.
+func (hw *HTTPWriter) spill(b []byte) error {
+	if err := os.MkdirAll(hw.SpillDir, 0o755); err != nil {
+		return err
+	}
+	if hw.MaxSpillBytes > 0 {
+		files, size, err := hw.spilled()
+		if err != nil {
+			return err
+		}
+		if size+int64(len(b)) > hw.MaxSpillBytes {
+			return fmt.Errorf("spill directory is full (%d files, %d bytes), batch dropped", len(files), size)
+		}
+	}
+
+	// Write to a temporary file first so that a partial batch is never sent
+	name := filepath.Join(hw.SpillDir, fmt.Sprintf("%020d", time.Now().UnixNano()))
+	if err := os.WriteFile(name+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name+spillExt)
+}
+
+// spilled returns the spilled batches (oldest first) and their total size.
+func (hw *HTTPWriter) spilled() ([]string, int64, error) {
+	entries, err := os.ReadDir(hw.SpillDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	var files []string
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spillExt) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		files = append(files, filepath.Join(hw.SpillDir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, size, nil
+}
+
+// resend sends the spilled batches, oldest first, stopping at the first failure (they are tried again later).
+// Batches rejected by the endpoint are deleted, since sending them again would fail again.
+func (hw *HTTPWriter) resend() {
+	if hw.SpillDir == "" {
+		return
+	}
+	files, _, err := hw.spilled()
+	if err != nil {
+		hw.report(err)
+		return
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			hw.report(err)
+			return
+		}
+		if err := hw.post(b); err != nil && !isPermanent(err) {
+			return // Still unreachable
+		} else if err != nil {
+			hw.report(err)
+		}
+		if err := os.Remove(name); err != nil {
+			hw.report(err)
+			return
+		}
+	}
+}
+
+// report passes an error of the background goroutine to OnError (if set).
+func (hw *HTTPWriter) report(err error) {
+	if hw.OnError != nil {
+		hw.OnError(err)
+	}
+}
+
+// batchSize returns the configured batch size or the default one.
+func (hw *HTTPWriter) batchSize() int {
+	if hw.BatchSize <= 0 {
+		return DefaultHTTPBatchSize
+	}
+	return hw.BatchSize
+}
@@ -0,0 +1,49 @@
+package writers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPWriterKeepsLogsInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		time.Sleep(5 * time.Millisecond) // Let batches pile up in the queue
+		mu.Lock()
+		received = append(received, strings.Fields(string(b))...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	hw := NewHTTPWriter(srv.URL)
+	hw.BatchSize = 8
+	hw.FlushInterval = time.Millisecond
+	for i := 0; i < 200; i++ {
+		if _, err := hw.Write([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Microsecond) // Leave incomplete batches for the periodic flushes
+	}
+	if err := hw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 200 {
+		t.Fatalf("%d logs received, want 200", len(received))
+	}
+	for i, line := range received {
+		if line != strconv.Itoa(i) {
+			t.Fatalf("log %d = %s, want %d", i, line, i)
+		}
+	}
+}
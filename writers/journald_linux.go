@@ -0,0 +1,63 @@
+//go:build linux
+
+package writers
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Path of the socket of the native protocol of systemd-journald.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter sends entries to systemd-journald using its native protocol,
+// each write must hold a single entry in this protocol (for ex: serialized with logs.AsJournal).
+//
+// Entries too large for a datagram are written to an unlinked temporary file in /dev/shm
+// and the file descriptor is passed to journald instead.
+type JournaldWriter struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// Journald returns a writer sending entries to the local journald socket.
+func Journald() (*JournaldWriter, error) {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{conn: conn, addr: &net.UnixAddr{Name: JournaldSocket, Net: "unixgram"}}, nil
+}
+
+// Write sends an entry to journald.
+func (jw *JournaldWriter) Write(b []byte) (int, error) {
+	_, _, err := jw.conn.WriteMsgUnix(b, nil, jw.addr)
+	if err == nil {
+		return len(b), nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return 0, err
+	}
+
+	// Pass the entry as a file
+	f, err := os.CreateTemp("/dev/shm", "journal.*")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(b); err != nil {
+		return 0, err
+	}
+	if _, _, err := jw.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), jw.addr); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the socket.
+func (jw *JournaldWriter) Close() error { return jw.conn.Close() }
@@ -0,0 +1,157 @@
+package writers
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrBufferFull is returned when a write is dropped because the buffer of a writer is full.
+var ErrBufferFull = errors.New("buffer is full")
+
+// NetWriter ships logs to a network endpoint (for ex: Logstash, Fluentd or Vector TCP inputs).
+// Writes are buffered and sent by a background goroutine,
+// which reconnects with an exponential backoff when the connection fails.
+type NetWriter struct {
+	network string
+	addr    string
+	config  netConfig
+	queue   chan []byte
+	done    chan struct{}
+
+	mu     sync.RWMutex // Guards closed (read-locked while queueing)
+	closed bool
+}
+
+// NetOption configures a NetWriter.
+type NetOption func(*netConfig)
+
+type netConfig struct {
+	tls         *tls.Config
+	bufferSize  int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	dialTimeout time.Duration
+}
+
+// WithTLS enables TLS with the given configuration.
+func WithTLS(config *tls.Config) NetOption { return func(c *netConfig) { c.tls = config } }
+
+// WithBufferSize sets the maximum number of writes buffered (1024 by default).
+func WithBufferSize(n int) NetOption { return func(c *netConfig) { c.bufferSize = n } }
+
+// WithBackoff sets the minimum and maximum delays between reconnection attempts (100ms and 30s by default).
+func WithBackoff(min, max time.Duration) NetOption {
+	return func(c *netConfig) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// Net returns a writer shipping logs to the given address (for ex: "tcp", "localhost:5170").
+// The connection is established in the background, so Net never fails.
+func Net(network, addr string, opts ...NetOption) *NetWriter {
+	config := netConfig{
+		bufferSize:  1024,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
+		dialTimeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	nw := &NetWriter{
+		network: network,
+		addr:    addr,
+		config:  config,
+		queue:   make(chan []byte, config.bufferSize),
+		done:    make(chan struct{}),
+	}
+	go nw.run()
+	return nw
+}
+
+// Write buffers a copy of b to be sent, it fails if the buffer is full.
+func (nw *NetWriter) Write(b []byte) (int, error) {
+	nw.mu.RLock()
+	defer nw.mu.RUnlock()
+	if nw.closed {
+		return 0, net.ErrClosed
+	}
+	select {
+	case nw.queue <- append([]byte(nil), b...):
+		return len(b), nil
+	default:
+		return 0, ErrBufferFull
+	}
+}
+
+// Close stops accepting writes and waits for the buffered ones to be sent.
+// Buffered writes are discarded if the connection fails while closing.
+func (nw *NetWriter) Close() error {
+	nw.mu.Lock()
+	if nw.closed {
+		nw.mu.Unlock()
+		return nil
+	}
+	nw.closed = true
+	close(nw.queue)
+	nw.mu.Unlock()
+
+	<-nw.done
+	return nil
+}
+
+// run sends buffered writes, reconnecting when needed.
+func (nw *NetWriter) run() {
+	defer close(nw.done)
+
+	var conn net.Conn
+	backoff := time.Duration(0)
+	for b := range nw.queue {
+		for {
+			var err error
+			if conn == nil {
+				conn, err = nw.dial()
+			}
+			if err == nil {
+				if _, err = conn.Write(b); err == nil {
+					backoff = 0
+					break
+				}
+				conn.Close()
+				conn = nil
+			}
+
+			// Give up on remaining writes when closing
+			nw.mu.RLock()
+			closed := nw.closed
+			nw.mu.RUnlock()
+			if closed {
+				return
+			}
+
+			// Wait before retrying
+			backoff *= 2
+			if backoff < nw.config.minBackoff {
+				backoff = nw.config.minBackoff
+			}
+			if backoff > nw.config.maxBackoff {
+				backoff = nw.config.maxBackoff
+			}
+			time.Sleep(backoff)
+		}
+	}
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// dial connects to the configured address.
+func (nw *NetWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: nw.config.dialTimeout}
+	if nw.config.tls != nil {
+		return tls.DialWithDialer(dialer, nw.network, nw.addr, nw.config.tls)
+	}
+	return dialer.Dial(nw.network, nw.addr)
+}
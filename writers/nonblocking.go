@@ -0,0 +1,81 @@
+package writers
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// NonBlockingWriter queues writes and performs them from a background goroutine,
+// so that a slow destination never blocks the caller.
+// When the queue is full, writes are dropped.
+type NonBlockingWriter struct {
+	w       io.Writer
+	queue   chan []byte
+	done    chan struct{}
+	onDrop  func(n int)
+	dropped uint64 // Accessed atomically
+
+	mu     sync.RWMutex // Guards closed (read-locked while queueing)
+	closed bool
+}
+
+// NonBlocking returns a writer queueing at most queueSize writes to w.
+// The optional onDrop function is called with the number of bytes of each dropped write.
+func NonBlocking(w io.Writer, queueSize int, onDrop func(n int)) *NonBlockingWriter {
+	nb := &NonBlockingWriter{
+		w:      w,
+		queue:  make(chan []byte, queueSize),
+		done:   make(chan struct{}),
+		onDrop: onDrop,
+	}
+	go func() {
+		for b := range nb.queue {
+			nb.w.Write(b)
+		}
+		close(nb.done)
+	}()
+	return nb
+}
+
+// Write queues a copy of b, dropping it if the queue is full or the writer is closed.
+// It never fails: drops are reported to the onDrop function and counted (see Dropped).
+func (nb *NonBlockingWriter) Write(b []byte) (int, error) {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	if !nb.closed {
+		select {
+		case nb.queue <- append([]byte(nil), b...):
+			return len(b), nil
+		default:
+		}
+	}
+
+	atomic.AddUint64(&nb.dropped, 1)
+	if nb.onDrop != nil {
+		nb.onDrop(len(b))
+	}
+	return len(b), nil
+}
+
+// Dropped returns the number of writes dropped so far.
+func (nb *NonBlockingWriter) Dropped() uint64 { return atomic.LoadUint64(&nb.dropped) }
+
+// Close waits for the queued writes and closes the underlying writer (if it implements io.Closer).
+func (nb *NonBlockingWriter) Close() error {
+	nb.mu.Lock()
+	if nb.closed {
+		nb.mu.Unlock()
+		return nil
+	}
+	nb.closed = true
+	close(nb.queue)
+	nb.mu.Unlock()
+
+	<-nb.done
+	if c, ok := nb.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package writers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Retention cleans up a log directory: it compresses and deletes old files
+// and deletes the oldest files when the directory gets too big.
+// The most recently modified matching file is never touched, since it is usually the one being written.
+type Retention struct {
+	Dir           string        // For ex: "/var/log/app"
+	Pattern       string        // For ex: "app.*" to only manage some files of the directory (all files by default, see filepath.Match)
+	CompressAfter time.Duration // For ex: 24h to gzip files once they are a day old (0 means files are not compressed)
+	DeleteAfter   time.Duration // For ex: 30 * 24h to delete files once they are 30 days old (0 means no age limit)
+	MaxTotalBytes int64         // For ex: 1 GB for all managed files (0 means no size limit)
+	Interval      time.Duration // For ex: 10m between cleanups in the background (1h by default)
+	OnError       func(error)   // For ex: write a fallback line to stderr (errors are ignored by default)
+}
+
+// Start cleans up the directory immediately, then periodically in the background.
+// The returned function stops the cleanups, waiting for the current one (if any) to finish.
+func (r *Retention) Start() (stop func()) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := r.Run(); err != nil && r.OnError != nil {
+				r.OnError(err)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// managedFile is a file handled by the retention policy.
+type managedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Run cleans up the directory once.
+// All files are handled even if some fail, the first error is returned.
+func (r *Retention) Run() error {
+	files, err := r.files()
+	if err != nil || len(files) <= 1 {
+		return err
+	}
+	files = files[:len(files)-1] // Keep current file
+
+	var firstErr error
+	now := time.Now()
+	for _, f := range files {
+		age := now.Sub(f.modTime)
+		switch {
+		case r.DeleteAfter > 0 && age >= r.DeleteAfter:
+			if err := os.Remove(f.path); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		case r.CompressAfter > 0 && age >= r.CompressAfter && !strings.HasSuffix(f.path, ".gz"):
+			if err := compressFile(f.path); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			os.Chtimes(f.path+".gz", f.modTime, f.modTime) // Keep age of the file
+		}
+	}
+
+	// Delete oldest files until the total size is below the limit
+	if r.MaxTotalBytes > 0 {
+		all, err := r.files()
+		if err != nil || len(all) <= 1 {
+			return err
+		}
+		var total int64
+		for _, f := range all {
+			total += f.size
+		}
+		for _, f := range all[:len(all)-1] {
+			if total <= r.MaxTotalBytes {
+				break
+			}
+			if err := os.Remove(f.path); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			total -= f.size
+		}
+	}
+	return firstErr
+}
+
+// files returns the managed files of the directory, from oldest to newest.
+func (r *Retention) files() ([]managedFile, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pattern := r.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	var files []managedFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if ok, err := filepath.Match(pattern, e.Name()); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // Removed in the meantime
+		}
+		files = append(files, managedFile{path: filepath.Join(r.Dir, e.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	return files, nil
+}
@@ -0,0 +1,281 @@
+package writers
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a file writer that rotates the file once it gets too big or too old,
+// so that long-running services don't fill the disk.
+//
+// Rotated files are renamed with their rotation time (for ex: "app.log" becomes "app.20060102T150405.000.log",
+// followed by a counter if several rotations happen in the same millisecond, for ex: "app.20060102T150405.000-1.log")
+// and optionally compressed with gzip.
+//
+// If the new file cannot be opened after a rotation, writes fail until it can be opened again (it is retried on each write).
+//
+// The age of a file opened again after a restart is counted from its last rotation
+// (or from its last modification if it was never rotated).
+type RotatingFile struct {
+	MaxBytes int64         // For ex: 100 MB (0 means no size limit)
+	MaxAge   time.Duration // For ex: 24h to rotate the file every day (0 means no age limit)
+	MaxFiles int           // For ex: 7 rotated files kept on disk (0 means all files are kept)
+	Compress bool          // For ex: true to gzip rotated files
+
+	path     string
+	mu       sync.Mutex
+	f        *os.File // Nil if the file could not be opened after a rotation (or if it is closed)
+	size     int64
+	openedAt time.Time
+	closed   bool
+	wg       sync.WaitGroup // Tracks background compressions
+}
+
+// Layout of the rotation time added to rotated file names.
+const rotationTimeLayout = "20060102T150405.000"
+
+// NewRotatingFile opens (or creates) the file at the given path, new logs are appended to it.
+func NewRotatingFile(path string) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	if rf.size > 0 { // The file may have been created by a previous process
+		if files := rotatedFiles(path); len(files) > 0 {
+			rf.openedAt = files[len(files)-1].t
+		} else if info, err := rf.f.Stat(); err == nil {
+			rf.openedAt = info.ModTime()
+		}
+	}
+	return rf, nil
+}
+
+// Write writes b to the current file, rotating it first if needed.
+func (rf *RotatingFile) Write(b []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	tooBig := rf.MaxBytes > 0 && rf.size > 0 && rf.size+int64(len(b)) > rf.MaxBytes
+	tooOld := rf.MaxAge > 0 && time.Since(rf.openedAt) >= rf.MaxAge
+	if tooBig || tooOld {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.f.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately (for ex: when receiving SIGHUP).
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	return rf.rotate()
+}
+
+// Sync commits the current file content to stable storage.
+func (rf *RotatingFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	if rf.f == nil {
+		return nil
+	}
+	return rf.f.Sync()
+}
+
+// Close closes the current file and waits for pending compressions, writes fail afterwards (with os.ErrClosed).
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return nil
+	}
+	rf.closed = true
+	var err error
+	if rf.f != nil {
+		err = rf.f.Close()
+		rf.f = nil
+	}
+	rf.wg.Wait()
+	return err
+}
+
+// open opens the file at the configured path.
+func (rf *RotatingFile) open() error {
+	if dir := filepath.Dir(rf.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size, rf.openedAt = f, info.Size(), time.Now()
+	return nil
+}
+
+// rotate renames the current file, opens a new one and removes old rotated files.
+// If the new file cannot be opened, f is left nil so that the next write opens it again.
+func (rf *RotatingFile) rotate() error {
+	if rf.f != nil {
+		if err := rf.f.Close(); err != nil {
+			return err
+		}
+		rf.f = nil
+	}
+	rotated := rf.rotatedPath(time.Now())
+	renameErr := os.Rename(rf.path, rotated)
+	if err := rf.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr // keep writing to the current file
+	}
+
+	if rf.Compress {
+		rf.wg.Add(1)
+		go func() {
+			defer rf.wg.Done()
+			if err := compressFile(rotated); err == nil {
+				rf.removeOldFiles()
+			}
+		}()
+		return nil
+	}
+	rf.removeOldFiles()
+	return nil
+}
+
+// rotatedPath returns the path of the file rotated at the given time,
+// its counter is greater than the ones of the files rotated in the same millisecond so that it is sorted after them.
+func (rf *RotatingFile) rotatedPath(t time.Time) string {
+	ext := filepath.Ext(rf.path)
+	formatted := t.Format(rotationTimeLayout)
+	counter := 0
+	for _, f := range rotatedFiles(rf.path) {
+		if f.t.Format(rotationTimeLayout) == formatted && f.counter >= counter {
+			counter = f.counter + 1
+		}
+	}
+	base := strings.TrimSuffix(rf.path, ext) + "." + formatted
+	if counter == 0 {
+		return base + ext
+	}
+	return base + "-" + strconv.Itoa(counter) + ext
+}
+
+// removeOldFiles removes the oldest rotated files when there are more than MaxFiles.
+func (rf *RotatingFile) removeOldFiles() {
+	if rf.MaxFiles <= 0 {
+		return
+	}
+	files := RotatedFiles(rf.path)
+	for len(files) > rf.MaxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+}
+
+// RotatedFiles returns the paths of the files rotated from the given path, from oldest to newest.
+func RotatedFiles(path string) []string {
+	rotated := rotatedFiles(path)
+	files := make([]string, len(rotated))
+	for i, f := range rotated {
+		files[i] = f.path
+	}
+	return files
+}
+
+// rotatedFile is a file rotated at the given time.
+type rotatedFile struct {
+	path    string
+	t       time.Time
+	counter int
+}
+
+// rotatedFiles returns the files rotated from the given path, from oldest to newest.
+func rotatedFiles(path string) []rotatedFile {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "."
+	matches, _ := filepath.Glob(prefix + "*")
+	rotated := []rotatedFile{}
+	for _, m := range matches {
+		name := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(m, ".gz"), ext), prefix)
+		counter := 0
+		if i := strings.LastIndexByte(name, '-'); i >= 0 {
+			n, err := strconv.Atoi(name[i+1:])
+			if err != nil || n <= 0 {
+				continue
+			}
+			name, counter = name[:i], n
+		}
+		if t, err := time.ParseInLocation(rotationTimeLayout, name, time.Local); err == nil {
+			rotated = append(rotated, rotatedFile{path: m, t: t, counter: counter})
+		}
+	}
+	sort.Slice(rotated, func(i, j int) bool {
+		if !rotated[i].t.Equal(rotated[j].t) {
+			return rotated[i].t.Before(rotated[j].t)
+		}
+		return rotated[i].counter < rotated[j].counter
+	})
+	return rotated
+}
+
+// compressFile replaces a file with its gzipped version.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
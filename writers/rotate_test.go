@@ -0,0 +1,206 @@
+package writers
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readFile returns the content of a file, decompressing it if it is gzipped.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if filepath.Ext(path) == ".gz" {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// write writes a line, failing the test on error.
+func write(t *testing.T, w io.Writer, line string) {
+	t.Helper()
+	if _, err := w.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotatingFileRotatesWhenTooBig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.MaxBytes = 10
+	write(t, rf, "first\n")
+	write(t, rf, "second\n") // Exceeds 10 bytes
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) != 1 {
+		t.Fatalf("%d rotated files, want 1", len(rotated))
+	}
+	if got := readFile(t, rotated[0]); got != "first\n" {
+		t.Fatalf("rotated file = %q, want %q", got, "first\n")
+	}
+	if got := readFile(t, path); got != "second\n" {
+		t.Fatalf("current file = %q, want %q", got, "second\n")
+	}
+}
+
+func TestRotatingFileRotatesWhenTooOld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.MaxAge = 20 * time.Millisecond
+	write(t, rf, "first\n")
+	write(t, rf, "second\n")
+	time.Sleep(30 * time.Millisecond)
+	write(t, rf, "third\n")
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) != 1 {
+		t.Fatalf("%d rotated files, want 1", len(rotated))
+	}
+	if got := readFile(t, rotated[0]); got != "first\nsecond\n" {
+		t.Fatalf("rotated file = %q, want %q", got, "first\nsecond\n")
+	}
+}
+
+func TestRotatingFileAgeIsKeptAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	lastRotation := time.Now().Add(-2 * time.Hour)
+	old := filepath.Join(dir, "app."+lastRotation.Format(rotationTimeLayout)+".log")
+	if err := os.WriteFile(old, []byte("older\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("before the restart\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.MaxAge = time.Hour
+	write(t, rf, "after the restart\n")
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, path); got != "after the restart\n" {
+		t.Fatalf("current file = %q, want %q (the file opened 2h ago should have been rotated)", got, "after the restart\n")
+	}
+}
+
+func TestRotatingFileCompressesRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.Compress = true
+	write(t, rf, "compressed\n")
+	if err := rf.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Close(); err != nil { // Waits for the compression
+		t.Fatal(err)
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) != 1 || filepath.Ext(rotated[0]) != ".gz" {
+		t.Fatalf("rotated files = %q, want a single gzipped file", rotated)
+	}
+	if got := readFile(t, rotated[0]); got != "compressed\n" {
+		t.Fatalf("rotated file = %q, want %q", got, "compressed\n")
+	}
+}
+
+func TestRotatingFileRemovesOldFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.MaxFiles = 2
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		write(t, rf, line)
+		if err := rf.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) != 2 {
+		t.Fatalf("%d rotated files, want 2", len(rotated))
+	}
+	if got := readFile(t, rotated[0]) + readFile(t, rotated[1]); got != "3\n4\n" {
+		t.Fatalf("rotated files hold %q, want the newest ones (%q)", got, "3\n4\n")
+	}
+}
+
+func TestRotatingFileReopensAfterFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, rf, "before\n")
+
+	// Simulate a rotation that could not open the new file
+	rf.f.Close()
+	rf.f = nil
+	os.Rename(path, rf.rotatedPath(time.Now()))
+
+	write(t, rf, "after\n")
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "after\n" {
+		t.Fatalf("current file = %q, want %q", got, "after\n")
+	}
+}
+
+func TestRotatingFileWriteAfterClose(t *testing.T) {
+	rf, err := NewRotatingFile(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("too late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("write after close: %v, want %v", err, os.ErrClosed)
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package writers
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Facility is a syslog facility (RFC 5424 section 6.2.1).
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityLocal0 Facility = iota + 4
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Severity is a syslog severity (RFC 5424 section 6.2.1).
+type Severity int
+
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+// SyslogWriter writes each log as an RFC 5424 syslog message.
+// The connection is re-dialed once when a write fails.
+type SyslogWriter struct {
+	Severity Severity // For ex: SeverityInfo (the default) for all messages written
+
+	network  string
+	addr     string
+	facility Facility
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Syslog returns a writer sending messages to the syslog daemon at the given address
+// (for ex: "udp", "localhost:514"). The local daemon is used if network is empty.
+// The tag is used as the application name of the messages.
+func Syslog(network, addr string, facility Facility, tag string) (*SyslogWriter, error) {
+	hostname, _ := os.Hostname()
+	sw := &SyslogWriter{
+		Severity: SeverityInfo,
+		network:  network,
+		addr:     addr,
+		facility: facility,
+		tag:      tag,
+		hostname: hostname,
+	}
+	if err := sw.connect(); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Write sends b (without trailing line break) as a syslog message.
+func (sw *SyslogWriter) Write(b []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	msg := sw.format(bytes.TrimRight(b, "\n"))
+	if sw.conn != nil {
+		if _, err := sw.conn.Write(msg); err == nil {
+			return len(b), nil
+		}
+		sw.conn.Close()
+		sw.conn = nil
+	}
+
+	// Reconnect and retry once
+	if err := sw.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := sw.conn.Write(msg); err != nil {
+		sw.conn.Close()
+		sw.conn = nil
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (sw *SyslogWriter) Close() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.conn == nil {
+		return nil
+	}
+	err := sw.conn.Close()
+	sw.conn = nil
+	return err
+}
+
+// format returns the RFC 5424 representation of a message.
+// Messages sent over stream connections are prefixed by their length (RFC 6587 octet counting).
+func (sw *SyslogWriter) format(b []byte) []byte {
+	hostname := sw.hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+	tag := sw.tag
+	if tag == "" {
+		tag = "-"
+	}
+	pri := int(sw.facility)*8 + int(sw.Severity)
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		pri, time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, tag, os.Getpid(), b)
+
+	if strings.HasPrefix(sw.network, "tcp") || sw.network == "unix" {
+		return []byte(strconv.Itoa(len(msg)) + " " + msg)
+	}
+	return []byte(msg)
+}
+
+// connect dials the syslog daemon.
+func (sw *SyslogWriter) connect() error {
+	if sw.network != "" {
+		conn, err := net.Dial(sw.network, sw.addr)
+		if err != nil {
+			return err
+		}
+		sw.conn = conn
+		return nil
+	}
+
+	// Find local daemon
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		if conn, err := net.Dial("unixgram", path); err == nil {
+			sw.conn = conn
+			return nil
+		}
+	}
+	return fmt.Errorf("no local syslog daemon found")
+}